@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -15,6 +17,7 @@ import (
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/database"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/handler"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/mail"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/service"
 )
@@ -22,9 +25,34 @@ import (
 func main() {
 	config.LoadConfig()
 	dbPool := database.NewConnection(context.Background())
-	defer dbPool.Close()
 	log.Println("Database connection established successfully")
 
+	// `server migrate ...` menjalankan migrasi lalu keluar tanpa menyalakan HTTP server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		code := runMigrateCommand(context.Background(), dbPool, os.Args[2:])
+		dbPool.Close()
+		os.Exit(code)
+	}
+	// `server user lock|unlock <email>` untuk admin mengunci akun
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		code := runUserCommand(context.Background(), dbPool, os.Args[2:])
+		dbPool.Close()
+		os.Exit(code)
+	}
+	defer dbPool.Close()
+
+	if config.Get("MIGRATE_ON_START") == "true" {
+		migrator, err := database.NewMigrator(dbPool)
+		if err != nil {
+			log.Fatalf("Failed to load migrations: %v", err)
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+		log.Printf("Database migrations up to date (%d applied)", len(applied))
+	}
+
 	// --- INISIALISASI LENGKAP & BENAR ---
 
 	// 1. Inisialisasi semua Repository
@@ -33,17 +61,36 @@ func main() {
 	roadmapRepo := repository.NewRoadmapRepository(dbPool)
 	taskRepo := repository.NewTaskRepository(dbPool)
 	reviewRepo := repository.NewReviewRepository(dbPool)
+	aiGenerationRepo := repository.NewAIGenerationRepository(dbPool)
+	aiUsageRepo := repository.NewAIUsageRepository(dbPool)
+	streakRepo := repository.NewStreakRepository(dbPool)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(dbPool)
+	accountTokenRepo := repository.NewAccountTokenRepository(dbPool)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(dbPool)
+
+	// Kunci penandatangan JWT (JWT_KEYS, atau JWT_SECRET_KEY untuk HS256)
+	jwtKeys, err := auth.LoadKeySet()
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+	signingKey := jwtKeys.Current(time.Now())
+	log.Printf("Signing access tokens with %s key %q", signingKey.Method.Alg(), signingKey.ID)
 
 	// 2. Inisialisasi semua Service
-	aiService := service.NewAIService()
-	authService := service.NewAuthService(userRepo)
-	goalService := service.NewGoalService(dbPool, goalRepo, roadmapRepo, aiService)
-	taskService := service.NewTaskService(dbPool, taskRepo, goalRepo, roadmapRepo, aiService, reviewRepo)
+	aiService := service.NewAIService(service.NewLLMProviderFromConfig(), aiGenerationRepo, userRepo)
+	quotaService := service.NewQuotaService(aiUsageRepo)
+	authService := service.NewAuthService(dbPool, userRepo, refreshTokenRepo, accountTokenRepo, loginAttemptRepo, jwtKeys, mail.NewMailerFromConfig())
+	streakService := service.NewStreakService(streakRepo)
+	goalService := service.NewGoalService(dbPool, goalRepo, roadmapRepo, aiService, quotaService)
+	taskService := service.NewTaskService(dbPool, taskRepo, goalRepo, roadmapRepo, aiService, reviewRepo, quotaService, streakService, goalService)
 
 	// 3. Inisialisasi semua Handler
 	authHandler := handler.NewAuthHandler(authService)
 	goalHandler := handler.NewGoalHandler(goalService)
 	taskHandler := handler.NewTaskHandler(taskService)
+	aiHandler := handler.NewAIHandler(aiService, quotaService)
+	statsHandler := handler.NewStatsHandler(streakService)
+	jwksHandler := handler.NewJWKSHandler(jwtKeys)
 
 	// --- AKHIR DARI PERUBAHAN ---
 
@@ -55,40 +102,75 @@ func main() {
 		AllowCredentials: true,
 		MaxAge:           300,
 	}).Handler)
+	// Di belakang proxy (misalnya Fly), ambil IP klien dari header yang diisi proxy untuk throttling login
+	if header := config.Get("CLIENT_IP_HEADER"); header != "" {
+		r.Use(handler.ClientIPFromHeader(header))
+	}
 	r.Use(middleware.Logger)
 
 	r.Get("/api/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Server is healthy and running!"))
 	})
+	r.Get("/api/health/ai", aiHandler.GetHealth)
+	r.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
 
 	r.Route("/api/auth", func(r chi.Router) {
 		r.Post("/register", authHandler.Register)
 		r.Post("/login", authHandler.Login)
-		r.Put("/api/auth/change-password", authHandler.ChangePassword)
+		r.Post("/refresh", authHandler.Refresh)
+		r.Post("/logout", authHandler.Logout)
+		r.Post("/verify-email/request", authHandler.RequestEmailVerification)
+		r.Post("/verify-email/confirm", authHandler.ConfirmEmailVerification)
+		r.Post("/password-reset/request", authHandler.RequestPasswordReset)
+		r.Post("/password-reset/confirm", authHandler.ResetPassword)
 	})
 
 	r.Group(func(r chi.Router) {
-		r.Use(auth.JwtMiddleware)
+		r.Use(auth.JwtMiddleware(jwtKeys, authService))
 		r.Get("/api/auth/me", authHandler.GetCurrentUser)
+		r.Delete("/api/auth/me", authHandler.DeleteAccount)
+		r.Put("/api/auth/change-password", authHandler.ChangePassword)
+		r.Post("/api/auth/logout-all", authHandler.LogoutAll)
 		r.Post("/api/goals", goalHandler.CreateGoal)
+		r.Get("/api/goals", goalHandler.ListGoals)
 		r.Get("/api/goals/active", goalHandler.GetActiveGoal)
+		r.Get("/api/goals/{goalId}", goalHandler.GetGoal)
 		r.Put("/api/goals/{goalId}", goalHandler.UpdateGoal)
+		r.Post("/api/goals/{goalId}/activate", goalHandler.ActivateGoal)
+		r.Post("/api/goals/{goalId}/deactivate", goalHandler.DeactivateGoal)
+		r.Put("/api/goals/{goalId}/priority", goalHandler.UpdateGoalPriority)
+		r.Put("/api/goals/{goalId}/target-date", goalHandler.UpdateGoalTargetDate)
+		r.Put("/api/goals/{goalId}/status", goalHandler.ChangeGoalStatus)
+		r.Get("/api/goals/{goalId}/history", goalHandler.GetGoalHistory)
 		r.Post("/api/goals/{goalId}/steps", goalHandler.AddRoadmapStep)
+		r.Post("/api/goals/{goalId}/roadmap/regenerate-tail", goalHandler.RegenerateRoadmapTail)
 		r.Put("/api/roadmap-steps/{stepId}", goalHandler.UpdateRoadmapStep)
 		r.Delete("/api/roadmap-steps/{stepId}", goalHandler.DeleteRoadmapStep)
 		r.Put("/api/roadmap/reorder", goalHandler.ReorderRoadmapSteps)
 		r.Put("/api/roadmap-steps/{stepId}/status", goalHandler.UpdateRoadmapStepStatus)
+		r.Get("/api/roadmap-steps/{stepId}/progress", taskHandler.GetStepProgress)
+		r.Get("/api/roadmap-steps/{stepId}/history", goalHandler.GetRoadmapStepHistory)
+		r.Post("/api/roadmap-steps/{stepId}/regenerate", goalHandler.RegenerateRoadmapStep)
 		
 		r.Post("/api/schedule/start-day", taskHandler.StartDay)
 		r.Get("/api/schedule/today", taskHandler.GetTodayScheduleReadOnly) // Ganti ke handler read-only
 		r.Post("/api/schedule/review", taskHandler.ReviewDay)
+		r.Post("/api/schedule/review/stream", taskHandler.ReviewDayStream)
 		r.Get("/api/schedule/history/{date}", taskHandler.GetHistoryByDate)
 		
 		r.Post("/api/tasks", taskHandler.CreateManualTask)
 		r.Put("/api/tasks/{taskId}", taskHandler.UpdateTaskTitle)
 		r.Delete("/api/tasks/{taskId}", taskHandler.DeleteTask)
 		r.Put("/api/tasks/{taskId}/status", taskHandler.UpdateTaskStatus)
+		r.Get("/api/tasks/{taskId}/history", taskHandler.GetTaskHistory)
 		r.Put("/api/tasks/{taskId}/deadline", taskHandler.UpdateTaskDeadline)
+
+		r.Get("/api/ai/generations", aiHandler.ListGenerations)
+		r.Get("/api/me/ai-usage", aiHandler.GetUsage)
+		r.Get("/api/me/login-attempts", authHandler.ListFailedLogins)
+		r.Put("/api/me/locale", authHandler.UpdateLocale)
+
+		r.Get("/api/stats/streak", statsHandler.GetStreak)
 	})
 	
 	port := config.Get("API_PORT")
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/database"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const migrateUsage = `Penggunaan: server migrate <perintah>
+
+Perintah:
+  up          Terapkan semua migrasi yang belum dijalankan
+  down [N]    Batalkan N migrasi terakhir (default 1)
+  status      Tampilkan versi database dan daftar migrasi
+  goto N      Migrasi naik/turun hingga versi N (0 = kosongkan database)
+  force N     Tandai database di versi N tanpa menjalankan SQL (setelah perbaikan manual)`
+
+// runMigrateCommand menjalankan subcommand `migrate` lalu mengembalikan exit code.
+func runMigrateCommand(ctx context.Context, dbPool *pgxpool.Pool, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	migrator, err := database.NewMigrator(dbPool)
+	if err != nil {
+		log.Printf("ERROR loading migrations: %v", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		return reportMigration("Diterapkan", applied, err)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, "N harus bilangan bulat positif")
+				return 2
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		return reportMigration("Dibatalkan", reverted, err)
+	case "goto", "force":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "N harus berupa nomor versi migrasi")
+			return 2
+		}
+		if args[0] == "force" {
+			if err := migrator.Force(ctx, uint(version)); err != nil {
+				log.Printf("ERROR forcing migration version: %v", err)
+				return 1
+			}
+			fmt.Printf("Versi database ditandai %d\n", version)
+			return 0
+		}
+		changed, err := migrator.Goto(ctx, uint(version))
+		return reportMigration("Diproses", changed, err)
+	case "status":
+		current, dirty, statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Printf("ERROR reading migration status: %v", err)
+			return 1
+		}
+		fmt.Printf("Versi database: %d (terbaru: %d)", current, migrator.Latest())
+		if dirty {
+			fmt.Print(" [DIRTY]")
+		}
+		fmt.Println()
+		for _, s := range statuses {
+			mark := " "
+			if s.Applied {
+				mark = "x"
+			}
+			fmt.Printf("  [%s] %06d_%s\n", mark, s.Version, s.Name)
+		}
+		return 0
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+}
+
+func reportMigration(verb string, versions []uint, err error) int {
+	for _, v := range versions {
+		fmt.Printf("%s: %06d\n", verb, v)
+	}
+	if err != nil {
+		log.Printf("ERROR running migrations: %v", err)
+		return 1
+	}
+	if len(versions) == 0 {
+		fmt.Println("Tidak ada perubahan")
+	}
+	return 0
+}
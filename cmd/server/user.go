@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/service"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const userUsage = `Penggunaan: server user <perintah> <email>
+
+Perintah:
+  lock <email>    Kunci akun: login ditolak dan semua token user langsung dicabut
+  unlock <email>  Buka kembali akun yang dikunci`
+
+// runUserCommand menjalankan subcommand admin `user` lalu mengembalikan exit code.
+// Instance server lain baru menolak token user setelah cache JWT_REVOCATION_CACHE_SECONDS habis.
+func runUserCommand(ctx context.Context, dbPool *pgxpool.Pool, args []string) int {
+	if len(args) != 2 || (args[0] != "lock" && args[0] != "unlock") {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+
+	// Perintah ini tidak menerbitkan token atau mengirim email, jadi kunci JWT dan mailer tidak perlu dimuat
+	authService := service.NewAuthService(dbPool, repository.NewUserRepository(dbPool), repository.NewRefreshTokenRepository(dbPool), nil, nil, nil, nil)
+	locked := args[0] == "lock"
+	if err := authService.SetAccountLocked(ctx, args[1], locked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			fmt.Fprintf(os.Stderr, "User %s tidak ditemukan\n", args[1])
+			return 1
+		}
+		log.Printf("ERROR updating account lock: %v", err)
+		return 1
+	}
+
+	if locked {
+		fmt.Printf("Akun %s dikunci\n", args[1])
+	} else {
+		fmt.Printf("Akun %s dibuka\n", args[1])
+	}
+	return 0
+}
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrMissingKeyID = errors.New("token has no kid header")
+)
+
+// LegacyKeyID adalah kid untuk kunci HS256 dari JWT_SECRET_KEY.
+const LegacyKeyID = "hs256"
+
+// SigningKey adalah satu kunci penandatangan JWT yang dikenali lewat kid.
+type SigningKey struct {
+	ID          string
+	Method      jwt.SigningMethod
+	ActiveFrom  time.Time   // Kunci baru dipakai menandatangani mulai waktu ini; sebelumnya hanya dipublikasikan
+	private     interface{} // crypto.Signer untuk RS256/EdDSA, []byte untuk HS256
+	public      interface{} // Kunci untuk verifikasi
+	publishable bool        // false untuk secret HMAC yang tidak boleh muncul di JWKS
+	verifyOnly  bool        // Hanya memverifikasi token lama, tidak pernah menandatangani
+}
+
+// KeySet berisi semua kunci yang diterima untuk verifikasi. Kunci penandatangan adalah kunci
+// dengan ActiveFrom terbaru yang sudah lewat, sehingga rotasi terjadi otomatis sesuai jadwal
+// tanpa restart. Kunci lama tetap diterima selama masih terdaftar.
+type KeySet struct {
+	keys []*SigningKey // Terurut berdasarkan ActiveFrom
+	byID map[string]*SigningKey
+}
+
+// LoadKeySet membaca kunci dari JWT_KEYS dengan format
+//
+//	kid=sumber[@aktif-mulai],kid=sumber[@aktif-mulai],...
+//
+// sumber berupa `file:/path/ke/kunci.pem` atau `env:NAMA_VARIABEL` (isi PEM di variabel
+// environment). aktif-mulai opsional dalam format RFC3339; akhiran @ yang bukan RFC3339 dianggap
+// bagian dari sumber. Kunci privat PEM boleh RSA (PKCS#1/PKCS#8, minimal 2048 bit, ditandatangani
+// RS256) atau Ed25519 (PKCS#8, EdDSA).
+//
+// Jika JWT_KEYS kosong, JWT_SECRET_KEY dipakai dengan HS256 (kid LegacyKeyID) seperti
+// sebelumnya agar deployment lama tetap jalan; dalam mode ini JWKS kosong. Jika keduanya diisi,
+// JWT_SECRET_KEY hanya dipakai memverifikasi token HS256 yang masih beredar selama migrasi ke
+// JWT_KEYS, dan sebaiknya dihapus setelah token tersebut kedaluwarsa.
+func LoadKeySet() (*KeySet, error) {
+	spec := strings.TrimSpace(config.Get("JWT_KEYS"))
+	secret := config.Get("JWT_SECRET_KEY")
+	if spec == "" {
+		if secret == "" {
+			return nil, errors.New("JWT_KEYS atau JWT_SECRET_KEY harus diisi")
+		}
+		return NewKeySet(legacyKey(secret))
+	}
+
+	var keys []*SigningKey
+	for _, entry := range strings.Split(spec, ",") {
+		key, err := parseKeyEntry(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if secret != "" {
+		log.Printf("JWT_SECRET_KEY masih diterima untuk memverifikasi token HS256 lama; hapus setelah token tersebut kedaluwarsa")
+		legacy := legacyKey(secret)
+		legacy.verifyOnly = true
+		keys = append(keys, legacy)
+	}
+	return NewKeySet(keys...)
+}
+
+func legacyKey(secret string) *SigningKey {
+	return &SigningKey{ID: LegacyKeyID, Method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+}
+
+// NewKeySet menyusun KeySet dari kunci yang sudah dimuat. kid wajib diisi dan harus unik, dan
+// minimal satu kunci harus bisa menandatangani.
+func NewKeySet(keys ...*SigningKey) (*KeySet, error) {
+	set := &KeySet{byID: map[string]*SigningKey{}}
+	signing := false
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("setiap kunci JWT harus punya kid")
+		}
+		signing = signing || !key.verifyOnly
+		if _, dup := set.byID[key.ID]; dup {
+			return nil, fmt.Errorf("kid JWT %q terdaftar lebih dari sekali", key.ID)
+		}
+		set.byID[key.ID] = key
+		set.keys = append(set.keys, key)
+	}
+	if !signing {
+		return nil, errors.New("minimal satu kunci JWT untuk menandatangani diperlukan")
+	}
+	sort.SliceStable(set.keys, func(i, j int) bool { return set.keys[i].ActiveFrom.Before(set.keys[j].ActiveFrom) })
+	return set, nil
+}
+
+func parseKeyEntry(entry string) (*SigningKey, error) {
+	kid, source, ok := strings.Cut(entry, "=")
+	if !ok || kid == "" || source == "" {
+		return nil, fmt.Errorf("entri JWT_KEYS %q harus berformat kid=sumber[@aktif-mulai]", entry)
+	}
+
+	// Akhiran @ hanya dianggap waktu aktif jika berupa RFC3339, agar path yang mengandung @
+	// (misalnya /run/secrets/jwt@v2.pem) tetap terbaca utuh.
+	var activeFrom time.Time
+	if at := strings.LastIndex(source, "@"); at >= 0 {
+		if t, err := time.Parse(time.RFC3339, source[at+1:]); err == nil {
+			source, activeFrom = source[:at], t
+		}
+	}
+
+	var data []byte
+	switch kind, ref, _ := strings.Cut(source, ":"); kind {
+	case "file":
+		raw, err := os.ReadFile(ref)
+		if err != nil {
+			return nil, fmt.Errorf("membaca kunci %q: %w", kid, err)
+		}
+		data = raw
+	case "env":
+		raw := config.Get(ref)
+		if raw == "" {
+			return nil, fmt.Errorf("variabel %s untuk kunci %q kosong", ref, kid)
+		}
+		// Secret manager sering menyimpan PEM dalam satu baris dengan \n literal
+		if !strings.Contains(raw, "\n") {
+			raw = strings.ReplaceAll(raw, `\n`, "\n")
+		}
+		data = []byte(raw)
+	default:
+		return nil, fmt.Errorf("sumber kunci %q harus diawali file: atau env:", kid)
+	}
+
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("kunci %q: %w", kid, err)
+	}
+	key.ID, key.ActiveFrom = kid, activeFrom
+	return key, nil
+}
+
+func parsePrivateKey(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("bukan kunci PEM")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("tipe PEM %q tidak didukung", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("kunci RSA minimal 2048 bit")
+		}
+		return &SigningKey{Method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey, publishable: true}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{Method: jwt.SigningMethodEdDSA, private: k, public: k.Public(), publishable: true}, nil
+	default:
+		return nil, fmt.Errorf("jenis kunci %T tidak didukung (gunakan RSA atau Ed25519)", parsed)
+	}
+}
+
+// Current mengembalikan kunci yang dipakai menandatangani token pada waktu now. Jika belum ada
+// kunci yang aktif, kunci dengan ActiveFrom paling awal yang dipakai.
+func (s *KeySet) Current(now time.Time) *SigningKey {
+	var current *SigningKey
+	for _, key := range s.keys {
+		if key.verifyOnly {
+			continue
+		}
+		if current == nil || !key.ActiveFrom.After(now) {
+			current = key
+		}
+	}
+	return current
+}
+
+// Sign menandatangani claims dengan kunci yang aktif saat ini dan mencantumkan kid-nya di header.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key := s.Current(time.Now())
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Keyfunc memilih kunci verifikasi berdasarkan kid di header token; token tanpa kid ditolak.
+// Algoritma token harus sama dengan algoritma kunci agar token HS256 tidak bisa memakai kunci
+// publik sebagai secret.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrMissingKeyID
+	}
+	key, ok := s.byID[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.public, nil
+}
+
+// Parse memvalidasi signature dan masa berlaku token lalu mengisi claims.
+func (s *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, s.Keyfunc, jwt.WithValidMethods(s.methods()))
+}
+
+func (s *KeySet) methods() []string {
+	var methods []string
+	seen := map[string]bool{}
+	for _, key := range s.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWK adalah kunci publik dalam format JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // Modulus RSA
+	E   string `json:"e,omitempty"`   // Eksponen RSA
+	Crv string `json:"crv,omitempty"` // Kurva OKP
+	X   string `json:"x,omitempty"`   // Kunci publik Ed25519
+}
+
+// JWKS mengembalikan semua kunci publik, termasuk kunci yang dijadwalkan aktif nanti, agar
+// layanan lain sudah menyimpannya sebelum kunci itu mulai dipakai.
+func (s *KeySet) JWKS() []JWK {
+	jwks := []JWK{}
+	for _, key := range s.keys {
+		if !key.publishable {
+			continue
+		}
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKeyFile menyimpan kunci privat sebagai PEM PKCS#8 di dir dan mengembalikan path-nya.
+func writeKeyFile(t *testing.T, dir, name string, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func TestParseKeyEntry(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "secrets@v2") // Path dengan @ tidak boleh terpotong
+	path := writeKeyFile(t, dir, "jwt@2026.pem", newEd25519Key(t))
+
+	tests := []struct {
+		name           string
+		entry          string
+		wantActiveFrom time.Time
+		wantErr        string
+	}{
+		{name: "path containing @", entry: "k1=file:" + path},
+		{
+			name:           "path containing @ with activation time",
+			entry:          "k1=file:" + path + "@2026-05-01T00:00:00Z",
+			wantActiveFrom: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{name: "non-RFC3339 suffix is part of the path", entry: "k1=file:" + path + "@besok", wantErr: "membaca kunci"},
+		{name: "missing source", entry: "k1=", wantErr: "harus berformat"},
+		{name: "unknown source kind", entry: "k1=vault:jwt", wantErr: "harus diawali file: atau env:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseKeyEntry(tt.entry)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key.ID != "k1" || key.Method != jwt.SigningMethodEdDSA || !key.ActiveFrom.Equal(tt.wantActiveFrom) {
+				t.Errorf("got kid %q method %v active from %v", key.ID, key.Method.Alg(), key.ActiveFrom)
+			}
+		})
+	}
+}
+
+func TestKeyfunc(t *testing.T) {
+	dir := t.TempDir()
+	edKey, err := parseKeyEntry("ed1=file:" + writeKeyFile(t, dir, "ed.pem", newEd25519Key(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := legacyKey("rahasia-lama")
+	legacy.verifyOnly = true
+	set, err := NewKeySet(edKey, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()}
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("signing: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "current key", token: sign(jwt.SigningMethodEdDSA, "ed1", edKey.private)},
+		{name: "verify-only legacy HS256", token: sign(jwt.SigningMethodHS256, LegacyKeyID, []byte("rahasia-lama"))},
+		{name: "missing kid", token: sign(jwt.SigningMethodHS256, "", []byte("rahasia-lama")), wantErr: ErrMissingKeyID},
+		{name: "unknown kid", token: sign(jwt.SigningMethodHS256, "lain", []byte("rahasia-lama")), wantErr: ErrUnknownKey},
+		{name: "algorithm does not match the key", token: sign(jwt.SigningMethodHS256, "ed1", []byte("rahasia-lama")), wantErr: jwt.ErrTokenSignatureInvalid},
+		{name: "legacy secret is wrong", token: sign(jwt.SigningMethodHS256, LegacyKeyID, []byte("tebakan")), wantErr: jwt.ErrTokenSignatureInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := set.Parse(tt.token, jwt.MapClaims{})
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Kunci legacy yang verify-only tidak pernah dipakai menandatangani
+	if current := set.Current(time.Now()); current.ID != "ed1" {
+		t.Errorf("current key = %q, want ed1", current.ID)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPriv := newEd25519Key(t)
+
+	dir := t.TempDir()
+	rsaKey, err := parseKeyEntry("rs1=file:" + writeKeyFile(t, dir, "rs.pem", rsaPriv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, err := parseKeyEntry("ed2=file:" + writeKeyFile(t, dir, "ed.pem", edPriv) + "@2099-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := legacyKey("rahasia-lama")
+	legacy.verifyOnly = true
+	set, err := NewKeySet(edKey, legacy, rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := set.JWKS()
+	if len(jwks) != 2 {
+		t.Fatalf("got %d keys, want 2 (the HMAC secret must not be published): %+v", len(jwks), jwks)
+	}
+
+	rs, ed := jwks[0], jwks[1] // Terurut berdasarkan ActiveFrom; kunci terjadwal ikut dipublikasikan
+	if rs.Kty != "RSA" || rs.Kid != "rs1" || rs.Alg != "RS256" || rs.Use != "sig" || rs.Crv != "" || rs.X != "" {
+		t.Errorf("unexpected RSA JWK: %+v", rs)
+	}
+	if n, _ := base64.RawURLEncoding.DecodeString(rs.N); string(n) != string(rsaPriv.N.Bytes()) {
+		t.Errorf("RSA modulus does not match the public key")
+	}
+	if rs.E != "AQAB" {
+		t.Errorf("RSA exponent = %q, want AQAB", rs.E)
+	}
+
+	if ed.Kty != "OKP" || ed.Crv != "Ed25519" || ed.Kid != "ed2" || ed.Alg != "EdDSA" || ed.Use != "sig" || ed.N != "" {
+		t.Errorf("unexpected Ed25519 JWK: %+v", ed)
+	}
+	if x, _ := base64.RawURLEncoding.DecodeString(ed.X); string(x) != string(edPriv.Public().(ed25519.PublicKey)) {
+		t.Errorf("Ed25519 public key does not match")
+	}
+}
+
+func TestJWKSLegacyOnly(t *testing.T) {
+	set, err := NewKeySet(legacyKey("rahasia"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwks := set.JWKS(); jwks == nil || len(jwks) != 0 {
+		t.Errorf("JWKS = %#v, want an empty non-nil list", jwks)
+	}
+}
@@ -3,10 +3,10 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
-	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -14,7 +14,31 @@ type contextKey string
 
 const UserIDKey contextKey = "user_id"
 
-func JwtMiddleware(next http.Handler) http.Handler {
+// TokenVersionClaim adalah claim JWT yang berisi token_version user saat token diterbitkan.
+const TokenVersionClaim = "ver"
+
+var (
+	ErrTokenRevoked  = errors.New("token has been revoked")
+	ErrAccountLocked = errors.New("account is locked")
+)
+
+// TokenValidator memeriksa apakah token dengan versi tersebut masih berlaku untuk user:
+// versinya belum dinaikkan, akunnya masih ada, dan tidak dikunci. Mengembalikan
+// ErrTokenRevoked atau ErrAccountLocked jika tidak.
+type TokenValidator interface {
+	ValidateTokenVersion(ctx context.Context, userID string, version int) error
+}
+
+// JwtMiddleware memvalidasi signature (kunci dipilih dari kid di header) dan masa berlaku JWT,
+// lalu memeriksa claim "ver" ke validator agar token yang sudah dicabut (ganti password, akun
+// dihapus atau dikunci) langsung ditolak.
+func JwtMiddleware(keys *KeySet, validator TokenValidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return jwtHandler(keys, validator, next)
+	}
+}
+
+func jwtHandler(keys *KeySet, validator TokenValidator, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 1. Ambil header Authorization
 		authHeader := r.Header.Get("Authorization")
@@ -35,12 +59,7 @@ func JwtMiddleware(next http.Handler) http.Handler {
 
 		// 3. Parse dan validasi token
 		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, http.ErrAbortHandler
-			}
-			return []byte(config.Get("JWT_SECRET_KEY")), nil
-		})
+		token, err := keys.Parse(tokenString, claims)
 
 		if err != nil || !token.Valid {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
@@ -54,6 +73,24 @@ func JwtMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// 5. Pastikan token belum dicabut. Token tanpa claim versi (terbit sebelum fitur ini) ditolak.
+		version, ok := claims[TokenVersionClaim].(float64)
+		if !ok {
+			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+			return
+		}
+		if err := validator.ValidateTokenVersion(r.Context(), userID, int(version)); err != nil {
+			switch {
+			case errors.Is(err, ErrAccountLocked):
+				http.Error(w, "Account is locked", http.StatusForbidden)
+			case errors.Is(err, ErrTokenRevoked):
+				http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+			default:
+				http.Error(w, "Failed to validate token", http.StatusInternalServerError)
+			}
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
 		// Lanjutkan ke handler berikutnya
 		next.ServeHTTP(w, r.WithContext(ctx))
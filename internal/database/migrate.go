@@ -0,0 +1,313 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migration/*.sql
+var migrationFS embed.FS
+
+// migrationLockID adalah kunci pg_advisory_lock agar hanya satu instance yang bermigrasi
+// pada satu waktu (misalnya saat beberapa mesin Fly start bersamaan).
+const migrationLockID int64 = 7_342_901_118
+
+// migrationFilePattern mengikuti penamaan golang-migrate: 000001_nama.up.sql / .down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration adalah satu pasang file up/down yang di-embed ke binary.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus adalah status satu migrasi untuk perintah `migrate status`.
+type MigrationStatus struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Migrator menjalankan migrasi yang di-embed dan mencatat versi di tabel schema_migrations.
+// Format tabelnya sama dengan golang-migrate (satu baris: version, dirty), sehingga database
+// yang sebelumnya dimigrasi dengan CLI `migrate` bisa langsung dilanjutkan.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration // Terurut naik berdasarkan Version
+}
+
+func NewMigrator(pool *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFS)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: pool, migrations: migrations}, nil
+}
+
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migration")
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca folder migrasi: %w", err)
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("versi migrasi tidak valid pada %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, "migration/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("versi migrasi %d dipakai oleh dua nama berbeda (%s, %s)", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrasi %06d_%s harus punya file .up.sql dan .down.sql", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest mengembalikan versi migrasi terbaru yang di-embed (0 jika tidak ada).
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up menjalankan semua migrasi yang belum diterapkan. Mengembalikan versi yang baru diterapkan.
+func (m *Migrator) Up(ctx context.Context) ([]uint, error) {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down membatalkan `steps` migrasi terakhir. Mengembalikan versi yang dibatalkan.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]uint, error) {
+	var changed []uint
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		idx := m.indexOf(current)
+		if current != 0 && idx < 0 {
+			return fmt.Errorf("versi database %d tidak dikenal oleh binary ini", current)
+		}
+		target := uint(0)
+		if idx-steps >= 0 {
+			target = m.migrations[idx-steps].Version
+		}
+		changed, err = m.migrateTo(ctx, conn, current, target)
+		return err
+	})
+	return changed, err
+}
+
+// Goto memigrasi database naik atau turun hingga tepat di versi target (0 = kosong).
+func (m *Migrator) Goto(ctx context.Context, target uint) ([]uint, error) {
+	if target != 0 && m.indexOf(target) < 0 {
+		return nil, fmt.Errorf("versi migrasi %d tidak ada", target)
+	}
+
+	var changed []uint
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if current != 0 && m.indexOf(current) < 0 {
+			return fmt.Errorf("versi database %d tidak dikenal oleh binary ini", current)
+		}
+		changed, err = m.migrateTo(ctx, conn, current, target)
+		return err
+	})
+	return changed, err
+}
+
+// Force menandai database berada di versi tertentu dan membersihkan flag dirty tanpa
+// menjalankan SQL apa pun. Dipakai setelah memperbaiki migrasi yang gagal secara manual.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && m.indexOf(version) < 0 {
+		return fmt.Errorf("versi migrasi %d tidak ada", version)
+	}
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			return setVersion(ctx, tx, version)
+		})
+	})
+}
+
+// Status mengembalikan versi database saat ini, flag dirty, dan daftar migrasi beserta statusnya.
+func (m *Migrator) Status(ctx context.Context) (uint, bool, []MigrationStatus, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return 0, false, nil, err
+	}
+	defer conn.Release()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return 0, false, nil, err
+	}
+	var current int64
+	var dirty bool
+	err = conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&current, &dirty)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, mig := range m.migrations {
+		statuses[i] = MigrationStatus{Version: mig.Version, Name: mig.Name, Applied: int64(mig.Version) <= current}
+	}
+	return uint(current), dirty, statuses, nil
+}
+
+// migrateTo menjalankan migrasi satu per satu dari current ke target. Setiap migrasi
+// dijalankan dalam transaksi bersama pembaruan versinya.
+func (m *Migrator) migrateTo(ctx context.Context, conn *pgxpool.Conn, current, target uint) ([]uint, error) {
+	var changed []uint
+
+	if target >= current {
+		for _, mig := range m.migrations {
+			if mig.Version <= current || mig.Version > target {
+				continue
+			}
+			log.Printf("Menerapkan migrasi %06d_%s", mig.Version, mig.Name)
+			if err := applyMigration(ctx, conn, mig.Up, mig.Version); err != nil {
+				return changed, fmt.Errorf("migrasi %06d_%s (up) gagal: %w", mig.Version, mig.Name, err)
+			}
+			changed = append(changed, mig.Version)
+		}
+		return changed, nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version > current || mig.Version <= target {
+			continue
+		}
+		previous := uint(0)
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		log.Printf("Membatalkan migrasi %06d_%s", mig.Version, mig.Name)
+		if err := applyMigration(ctx, conn, mig.Down, previous); err != nil {
+			return changed, fmt.Errorf("migrasi %06d_%s (down) gagal: %w", mig.Version, mig.Name, err)
+		}
+		changed = append(changed, mig.Version)
+	}
+	return changed, nil
+}
+
+func applyMigration(ctx context.Context, conn *pgxpool.Conn, sql string, newVersion uint) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return err
+		}
+		return setVersion(ctx, tx, newVersion)
+	})
+}
+
+// setVersion menulis ulang satu-satunya baris schema_migrations. Versi 0 berarti tabel kosong.
+func setVersion(ctx context.Context, tx pgx.Tx, version uint) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)", int64(version))
+	return err
+}
+
+// withLock mengambil satu koneksi, memegang advisory lock selama fn berjalan, lalu melepasnya.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("gagal mengambil lock migrasi: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			log.Printf("ERROR releasing migration lock: %v", err)
+		}
+	}()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		dirty BOOLEAN NOT NULL
+	)`)
+	return err
+}
+
+// currentVersion membaca versi database. Database yang dirty (migrasi sebelumnya gagal
+// di tengah jalan) harus diperbaiki manual lalu ditandai dengan `migrate force N`.
+func currentVersion(ctx context.Context, conn *pgxpool.Conn) (uint, error) {
+	var version int64
+	var dirty bool
+	err := conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("database dalam keadaan dirty pada versi %d; perbaiki secara manual lalu jalankan `migrate force %d`", version, version)
+	}
+	return uint(version), nil
+}
+
+func (m *Migrator) indexOf(version uint) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
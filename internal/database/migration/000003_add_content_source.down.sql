@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS source;
+ALTER TABLE goals DROP COLUMN IF EXISTS roadmap_source;
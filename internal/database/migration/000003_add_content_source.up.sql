@@ -0,0 +1,7 @@
+-- Menandai apakah roadmap/tugas dibuat oleh AI, template cadangan, atau manual oleh pengguna
+ALTER TABLE goals ADD COLUMN roadmap_source VARCHAR(20) NOT NULL DEFAULT 'ai';
+
+ALTER TABLE tasks ADD COLUMN source VARCHAR(20);
+UPDATE tasks SET source = CASE WHEN roadmap_step_id IS NULL THEN 'manual' ELSE 'ai' END;
+ALTER TABLE tasks ALTER COLUMN source SET NOT NULL;
+ALTER TABLE tasks ALTER COLUMN source SET DEFAULT 'manual';
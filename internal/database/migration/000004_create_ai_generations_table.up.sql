@@ -0,0 +1,21 @@
+-- Log setiap panggilan ke provider AI untuk debugging dan audit biaya
+CREATE TABLE ai_generations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    goal_id UUID REFERENCES goals(id) ON DELETE SET NULL,
+    kind VARCHAR(50) NOT NULL,          -- roadmap, daily_tasks, review_feedback
+    provider VARCHAR(50) NOT NULL,
+    attempt INT NOT NULL DEFAULT 1,     -- > 1 berarti percobaan perbaikan
+    prompt TEXT NOT NULL,
+    raw_response TEXT,
+    cleaned_json TEXT,
+    outcome VARCHAR(30) NOT NULL,       -- ok, invalid_output, provider_error
+    error_message TEXT,
+    latency_ms INT NOT NULL,
+    prompt_tokens INT,
+    completion_tokens INT,
+    total_tokens INT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_ai_generations_user_id_created_at ON ai_generations(user_id, created_at DESC);
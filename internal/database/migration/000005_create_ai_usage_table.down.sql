@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS ai_monthly_limit;
+ALTER TABLE users DROP COLUMN IF EXISTS ai_daily_limit;
+DROP TABLE IF EXISTS ai_usage;
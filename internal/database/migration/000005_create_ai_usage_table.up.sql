@@ -0,0 +1,11 @@
+-- Penghitung pemakaian generasi AI per user per hari (dasar kuota harian & bulanan)
+CREATE TABLE ai_usage (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    usage_date DATE NOT NULL,
+    generation_count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, usage_date)
+);
+
+-- Override kuota per user (NULL = pakai batas default dari konfigurasi)
+ALTER TABLE users ADD COLUMN ai_daily_limit INT;
+ALTER TABLE users ADD COLUMN ai_monthly_limit INT;
@@ -0,0 +1,3 @@
+ALTER TABLE ai_generations DROP COLUMN IF EXISTS locale;
+ALTER TABLE ai_generations DROP COLUMN IF EXISTS prompt_version;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
@@ -0,0 +1,6 @@
+-- Preferensi bahasa user untuk prompt & konten AI (id, en)
+ALTER TABLE users ADD COLUMN locale VARCHAR(10) NOT NULL DEFAULT 'id';
+
+-- Catat versi template dan bahasa prompt pada log generasi AI
+ALTER TABLE ai_generations ADD COLUMN prompt_version VARCHAR(20);
+ALTER TABLE ai_generations ADD COLUMN locale VARCHAR(10);
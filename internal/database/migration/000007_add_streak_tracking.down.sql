@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS streak_days;
+ALTER TABLE users ALTER COLUMN current_streak DROP NOT NULL;
+ALTER TABLE users DROP COLUMN IF EXISTS streak_freezes;
+ALTER TABLE users DROP COLUMN IF EXISTS longest_streak;
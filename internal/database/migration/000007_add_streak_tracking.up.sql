@@ -0,0 +1,16 @@
+-- Rekor streak terpanjang dan jumlah "streak freeze" yang bisa dipakai untuk menutup hari yang terlewat
+ALTER TABLE users ADD COLUMN longest_streak INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN streak_freezes INT NOT NULL DEFAULT 0;
+UPDATE users SET current_streak = 0 WHERE current_streak IS NULL;
+ALTER TABLE users ALTER COLUMN current_streak SET NOT NULL;
+
+-- Riwayat streak per hari: counted (target tercapai), missed (di bawah target), frozen (ditutup freeze)
+CREATE TABLE streak_days (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    streak_date DATE NOT NULL,
+    outcome VARCHAR(20) NOT NULL,
+    completed_tasks INT NOT NULL DEFAULT 0,
+    total_tasks INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, streak_date)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_goals_user_active;
+ALTER TABLE goals DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,4 @@
+-- Bobot goal (1-10): semakin tinggi, semakin banyak porsi tugas harian untuk goal tersebut
+ALTER TABLE goals ADD COLUMN priority INT NOT NULL DEFAULT 1 CHECK (priority BETWEEN 1 AND 10);
+
+CREATE INDEX idx_goals_user_active ON goals (user_id) WHERE is_active = TRUE;
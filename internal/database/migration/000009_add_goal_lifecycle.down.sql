@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS goal_status_history;
+
+DROP INDEX IF EXISTS idx_goals_user_status;
+DROP INDEX IF EXISTS idx_goals_user_active;
+ALTER TABLE goals DROP COLUMN IF EXISTS is_active;
+ALTER TABLE goals ADD COLUMN is_active BOOLEAN DEFAULT TRUE;
+UPDATE goals SET is_active = (status = 'active');
+CREATE INDEX idx_goals_user_active ON goals (user_id) WHERE is_active = TRUE;
+
+ALTER TABLE goals DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE goals DROP COLUMN IF EXISTS abandoned_at;
+ALTER TABLE goals DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE goals DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE goals DROP COLUMN IF EXISTS status;
@@ -0,0 +1,32 @@
+-- Status goal eksplisit menggantikan flag is_active
+ALTER TABLE goals ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'paused', 'completed', 'abandoned', 'archived'));
+UPDATE goals SET status = CASE WHEN is_active THEN 'active' ELSE 'paused' END;
+
+ALTER TABLE goals ADD COLUMN status_changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE goals ADD COLUMN completed_at TIMESTAMPTZ;
+ALTER TABLE goals ADD COLUMN abandoned_at TIMESTAMPTZ;
+ALTER TABLE goals ADD COLUMN archived_at TIMESTAMPTZ;
+
+-- is_active kini diturunkan dari status agar query lama tetap berlaku
+DROP INDEX IF EXISTS idx_goals_user_active;
+ALTER TABLE goals DROP COLUMN is_active;
+ALTER TABLE goals ADD COLUMN is_active BOOLEAN GENERATED ALWAYS AS (status = 'active') STORED;
+CREATE INDEX idx_goals_user_active ON goals (user_id) WHERE is_active = TRUE;
+CREATE INDEX idx_goals_user_status ON goals (user_id, status);
+
+-- Riwayat perubahan status goal
+CREATE TABLE goal_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    goal_id UUID NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    note TEXT,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_goal_status_history_goal ON goal_status_history (goal_id, changed_at DESC);
+
+-- Status awal setiap goal yang sudah ada
+INSERT INTO goal_status_history (goal_id, from_status, to_status, changed_at)
+SELECT id, NULL, status, created_at FROM goals;
@@ -0,0 +1,5 @@
+ALTER TABLE roadmap_steps
+    DROP COLUMN IF EXISTS due_date,
+    DROP COLUMN IF EXISTS estimated_days;
+
+ALTER TABLE goals DROP COLUMN IF EXISTS target_date;
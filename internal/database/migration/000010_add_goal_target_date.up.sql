@@ -0,0 +1,7 @@
+-- Target tanggal opsional untuk goal, dipakai untuk menjadwalkan roadmap dan menghitung pacing
+ALTER TABLE goals ADD COLUMN target_date DATE;
+
+-- Estimasi durasi (hari) dari AI dan tanggal jatuh tempo hasil penjadwalan per langkah
+ALTER TABLE roadmap_steps
+    ADD COLUMN estimated_days INT CHECK (estimated_days > 0),
+    ADD COLUMN due_date DATE;
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS task_status_history;
+DROP TABLE IF EXISTS roadmap_step_status_history;
+
+ALTER TABLE tasks
+    DROP CONSTRAINT IF EXISTS tasks_status_check,
+    ALTER COLUMN status DROP NOT NULL;
+ALTER TABLE roadmap_steps
+    DROP CONSTRAINT IF EXISTS roadmap_steps_status_check,
+    ALTER COLUMN status DROP NOT NULL;
@@ -0,0 +1,52 @@
+-- Status kosong (NULL, default lama) berarti 'pending'; variasi huruf besar/spasi dari status
+-- yang dikenal dinormalisasi. Status lain tidak ditebak: migrasi gagal dan menampilkan nilainya
+-- agar bisa diperbaiki manual sebelum migrasi dijalankan ulang.
+UPDATE roadmap_steps SET status = 'pending' WHERE status IS NULL;
+UPDATE roadmap_steps SET status = LOWER(TRIM(status))
+WHERE status <> LOWER(TRIM(status)) AND LOWER(TRIM(status)) IN ('pending', 'in_progress', 'completed', 'skipped');
+UPDATE tasks SET status = 'pending' WHERE status IS NULL;
+UPDATE tasks SET status = LOWER(TRIM(status))
+WHERE status <> LOWER(TRIM(status)) AND LOWER(TRIM(status)) IN ('pending', 'completed', 'missed');
+
+DO $$
+DECLARE
+    unknown TEXT;
+BEGIN
+    SELECT string_agg(DISTINCT status, ', ') INTO unknown FROM roadmap_steps
+    WHERE status NOT IN ('pending', 'in_progress', 'completed', 'skipped');
+    IF unknown IS NOT NULL THEN
+        RAISE EXCEPTION 'roadmap_steps.status berisi nilai yang tidak dikenal: %', unknown;
+    END IF;
+
+    SELECT string_agg(DISTINCT status, ', ') INTO unknown FROM tasks
+    WHERE status NOT IN ('pending', 'completed', 'missed');
+    IF unknown IS NOT NULL THEN
+        RAISE EXCEPTION 'tasks.status berisi nilai yang tidak dikenal: %', unknown;
+    END IF;
+END $$;
+
+ALTER TABLE roadmap_steps
+    ALTER COLUMN status SET NOT NULL,
+    ADD CONSTRAINT roadmap_steps_status_check CHECK (status IN ('pending', 'in_progress', 'completed', 'skipped'));
+ALTER TABLE tasks
+    ALTER COLUMN status SET NOT NULL,
+    ADD CONSTRAINT tasks_status_check CHECK (status IN ('pending', 'completed', 'missed'));
+
+-- Riwayat perubahan status langkah roadmap dan tugas
+CREATE TABLE roadmap_step_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    step_id UUID NOT NULL REFERENCES roadmap_steps(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_roadmap_step_status_history_step ON roadmap_step_status_history (step_id, changed_at DESC);
+
+CREATE TABLE task_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_task_status_history_task ON task_status_history (task_id, changed_at DESC);
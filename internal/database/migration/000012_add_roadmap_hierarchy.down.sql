@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_roadmap_steps_parent;
+
+-- Sub-langkah tidak punya tempat di roadmap datar
+DELETE FROM roadmap_steps WHERE parent_id IS NOT NULL;
+
+ALTER TABLE roadmap_steps
+    DROP COLUMN IF EXISTS is_milestone,
+    DROP COLUMN IF EXISTS parent_id;
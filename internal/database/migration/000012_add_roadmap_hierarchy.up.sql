@@ -0,0 +1,7 @@
+-- Langkah roadmap bisa punya sub-langkah (satu tingkat) dan penanda milestone.
+-- step_order kini berlaku di antara langkah yang punya parent yang sama.
+ALTER TABLE roadmap_steps
+    ADD COLUMN parent_id UUID REFERENCES roadmap_steps(id) ON DELETE CASCADE,
+    ADD COLUMN is_milestone BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_roadmap_steps_parent ON roadmap_steps (goal_id, parent_id, step_order);
@@ -0,0 +1,16 @@
+-- Refresh token disimpan sebagai hash SHA-256; token aslinya hanya pernah dikirim ke klien.
+-- Setiap login memulai satu family; rotasi menghasilkan token baru dalam family yang sama.
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    user_agent TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,    -- Diisi saat token dirotasi; dipakai lagi berarti token bocor
+    revoked_at TIMESTAMPTZ  -- Diisi saat logout atau saat family dicabut
+);
+
+CREATE INDEX idx_refresh_tokens_user ON refresh_tokens (user_id);
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens (family_id);
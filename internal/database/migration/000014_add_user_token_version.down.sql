@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS locked_at,
+    DROP COLUMN IF EXISTS token_version;
@@ -0,0 +1,5 @@
+-- token_version ikut ditanam di access token (claim "ver"). Menaikkannya membuat semua
+-- access token user yang sudah terbit tidak berlaku lagi.
+ALTER TABLE users
+    ADD COLUMN token_version INT NOT NULL DEFAULT 0,
+    ADD COLUMN locked_at TIMESTAMPTZ;
@@ -0,0 +1,17 @@
+-- Akun yang sudah ada sebelum verifikasi email dianggap sudah terverifikasi.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+UPDATE users SET email_verified_at = COALESCE(created_at, NOW());
+
+-- Token sekali pakai untuk verifikasi email dan reset password. Seperti refresh token,
+-- yang disimpan hanya hash SHA-256; token aslinya hanya dikirim lewat email.
+CREATE TABLE account_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ -- Diisi saat token dipakai atau digantikan token yang lebih baru
+);
+
+CREATE INDEX idx_account_tokens_user_purpose ON account_tokens (user_id, purpose);
@@ -0,0 +1,15 @@
+-- Riwayat percobaan login untuk throttling brute-force dan audit di akun user.
+-- email disimpan dalam huruf kecil; user_id kosong jika email tidak terdaftar.
+CREATE TABLE login_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    user_agent TEXT,
+    succeeded BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_login_attempts_email ON login_attempts (email, created_at);
+CREATE INDEX idx_login_attempts_ip ON login_attempts (ip_address, created_at);
+CREATE INDEX idx_login_attempts_user ON login_attempts (user_id, created_at);
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/service"
+)
+
+// writeAIError memetakan error dari alur generasi AI ke status HTTP yang bermakna.
+// Mengembalikan false jika err bukan error AI yang dikenali, agar pemanggil bisa
+// menangani sisanya sendiri.
+func writeAIError(w http.ResponseWriter, err error) bool {
+	var quotaErr *service.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(quotaErr.RetryAfter.Seconds()))))
+		writeJSONError(w, http.StatusTooManyRequests, fmt.Sprintf("AI %s generation quota exceeded", quotaErr.Period))
+		return true
+	}
+
+	var unavailableErr *service.AIUnavailableError
+	if errors.As(err, &unavailableErr) {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Max(1, math.Ceil(unavailableErr.RetryAfter.Seconds())))))
+		writeJSONError(w, http.StatusServiceUnavailable, "AI service is temporarily unavailable, please try again later")
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeJSONError(w, http.StatusGatewayTimeout, "AI took too long to respond, please try again")
+		return true
+	}
+
+	var outputErr *service.AIOutputError
+	if errors.As(err, &outputErr) {
+		writeJSONError(w, http.StatusBadGateway, "AI returned an invalid response, please try again")
+		return true
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/auth"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/service"
+)
+
+type AIHandler struct {
+	aiService    *service.AIService
+	quotaService *service.QuotaService
+}
+
+func NewAIHandler(aiService *service.AIService, quotaService *service.QuotaService) *AIHandler {
+	return &AIHandler{aiService: aiService, quotaService: quotaService}
+}
+
+// ListGenerations mengembalikan riwayat panggilan AI milik user yang sedang login.
+// Query opsional: ?limit=20&offset=0 (limit maksimal 100).
+func (h *AIHandler) ListGenerations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSONError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	generations, err := h.aiService.ListGenerations(r.Context(), userID, limit, offset)
+	if err != nil {
+		log.Printf("ERROR listing AI generations: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list AI generations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(generations)
+}
+
+// GetUsage mengembalikan pemakaian dan sisa kuota generasi AI milik user.
+func (h *AIHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	usage, err := h.quotaService.GetUsage(r.Context(), userID)
+	if err != nil {
+		log.Printf("ERROR getting AI usage: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get AI usage")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(usage)
+}
+
+// GetHealth menampilkan state circuit breaker provider AI. Selalu 200 karena aplikasi
+// tetap berjalan (dengan template cadangan) walaupun sirkuit sedang terbuka.
+func (h *AIHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.aiService.Health())
+}
@@ -2,9 +2,16 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/auth"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/service"
@@ -44,6 +51,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	_, err := h.authService.RegisterUser(r.Context(), payload.Email, payload.Password)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidEmail) {
+			writeJSONError(w, http.StatusBadRequest, "Invalid email address")
+			return
+		}
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			writeJSONError(w, http.StatusConflict, "Email already exists")
@@ -65,15 +76,167 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    token, err := h.authService.LoginUser(r.Context(), payload.Email, payload.Password)
+    tokens, err := h.authService.LoginUser(r.Context(), payload.Email, payload.Password, r.UserAgent(), clientIP(r))
     if err != nil {
-        writeJSONError(w, http.StatusUnauthorized, err.Error())
+        var throttledErr *service.LoginThrottledError
+        if errors.As(err, &throttledErr) {
+            w.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Max(1, math.Ceil(throttledErr.RetryAfter.Seconds())))))
+            if throttledErr.Locked {
+                writeJSONError(w, http.StatusTooManyRequests, "Account temporarily locked after too many failed login attempts")
+                return
+            }
+            writeJSONError(w, http.StatusTooManyRequests, "Too many failed login attempts, please try again later")
+            return
+        }
+        if errors.Is(err, service.ErrInvalidCredentials) {
+            writeJSONError(w, http.StatusUnauthorized, err.Error())
+            return
+        }
+        if errors.Is(err, auth.ErrAccountLocked) {
+            writeJSONError(w, http.StatusForbidden, "Account is locked")
+            return
+        }
+        if errors.Is(err, service.ErrEmailNotVerified) {
+            writeJSONError(w, http.StatusForbidden, "Email not verified")
+            return
+        }
+        log.Printf("ERROR logging in: %v", err)
+        writeJSONError(w, http.StatusInternalServerError, "Failed to log in")
         return
     }
 
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusOK)
-    json.NewEncoder(w).Encode(map[string]string{"token": token})
+    json.NewEncoder(w).Encode(tokens)
+}
+
+// clientIP mengambil IP klien dari RemoteAddr. Di belakang proxy, RemoteAddr sudah diganti
+// dengan IP asli oleh ClientIPFromHeader.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// ClientIPFromHeader mengganti RemoteAddr dengan IP dari header yang diisi proxy tepercaya
+// (misalnya Fly-Client-IP). Hanya dipakai jika semua request lewat proxy tersebut, karena
+// klien yang terhubung langsung bisa mengisi header itu sendiri.
+func ClientIPFromHeader(header string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := net.ParseIP(strings.TrimSpace(r.Header.Get(header))); ip != nil {
+				r.RemoteAddr = ip.String()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ListFailedLogins menampilkan riwayat login gagal ke akun user yang sedang login.
+// Query opsional: ?limit=20&offset=0 (limit maksimal 100).
+func (h *AuthHandler) ListFailedLogins(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSONError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	attempts, err := h.authService.ListFailedLogins(r.Context(), userID, limit, offset)
+	if err != nil {
+		log.Printf("ERROR listing failed logins: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list login attempts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(attempts)
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh menukar refresh token dengan pasangan token baru. Refresh token lama tidak
+// berlaku lagi setelah ditukar.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.RefreshToken == "" {
+		writeJSONError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	tokens, err := h.authService.RefreshTokens(r.Context(), payload.RefreshToken, r.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRefreshTokenReused):
+			writeJSONError(w, http.StatusUnauthorized, "Refresh token has already been used, please log in again")
+		case errors.Is(err, service.ErrInvalidRefreshToken):
+			writeJSONError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		case errors.Is(err, auth.ErrAccountLocked):
+			writeJSONError(w, http.StatusForbidden, "Account is locked")
+		default:
+			log.Printf("ERROR refreshing token: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to refresh token")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout mencabut sesi milik refresh token yang dikirim.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.RefreshToken == "" {
+		writeJSONError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), payload.RefreshToken); err != nil {
+		log.Printf("ERROR logging out: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll mencabut semua sesi user di semua perangkat.
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	if err := h.authService.LogoutAll(r.Context(), userID); err != nil {
+		log.Printf("ERROR logging out all sessions: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 type ChangePasswordPayload struct {
@@ -94,15 +257,58 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.authService.ChangePassword(r.Context(), userID, payload.OldPassword, payload.NewPassword)
+	// Semua sesi lama dicabut; klien harus memakai pasangan token baru di respons ini
+	tokens, err := h.authService.ChangePassword(r.Context(), userID, payload.OldPassword, payload.NewPassword, r.UserAgent())
 	if err != nil {
-		writeJSONError(w, http.StatusUnauthorized, err.Error())
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			writeJSONError(w, http.StatusUnauthorized, "Invalid old password")
+			return
+		}
+		if errors.Is(err, service.ErrPasswordRequired) {
+			writeJSONError(w, http.StatusBadRequest, "new_password is required")
+			return
+		}
+		log.Printf("ERROR changing password for user %s: %v", userID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to change password")
 		return
 	}
 
     w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-    json.NewEncoder(w).Encode(map[string]string{"message": "Password updated successfully"})
+    json.NewEncoder(w).Encode(struct {
+		Message string `json:"message"`
+		*service.TokenPair
+	}{"Password updated successfully", tokens})
+}
+
+type DeleteAccountPayload struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccount menghapus akun user yang sedang login beserta seluruh datanya.
+func (h *AuthHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var payload DeleteAccountPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Password == "" {
+		writeJSONError(w, http.StatusBadRequest, "password is required")
+		return
+	}
+
+	if err := h.authService.DeleteAccount(r.Context(), userID, payload.Password); err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			writeJSONError(w, http.StatusUnauthorized, "Invalid password")
+			return
+		}
+		log.Printf("ERROR deleting account: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to delete account")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
@@ -124,4 +330,122 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusOK)
     json.NewEncoder(w).Encode(user)
-}
\ No newline at end of file
+}
+
+type EmailPayload struct {
+	Email string `json:"email"`
+}
+
+type AccountTokenPayload struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password,omitempty"` // Hanya untuk reset password
+}
+
+// RequestEmailVerification mengirim ulang email verifikasi. Responsnya selalu sama, baik
+// email terdaftar maupun tidak.
+func (h *AuthHandler) RequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	h.requestAccountEmail(w, r, h.authService.RequestEmailVerification, "If the account exists and is not verified yet, a verification email has been sent")
+}
+
+// RequestPasswordReset mengirim email berisi link reset password. Responsnya selalu sama,
+// baik email terdaftar maupun tidak.
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	h.requestAccountEmail(w, r, h.authService.RequestPasswordReset, "If the account exists, a password reset email has been sent")
+}
+
+// requestAccountEmail selalu membalas 202 dengan pesan yang sama; email dikirim di background
+// sehingga status dan waktu respons tidak membocorkan apakah email terdaftar.
+func (h *AuthHandler) requestAccountEmail(w http.ResponseWriter, r *http.Request, request func(ctx context.Context, email string), message string) {
+	var payload EmailPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Email == "" {
+		writeJSONError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	request(r.Context(), payload.Email)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// ConfirmEmailVerification memverifikasi email memakai token dari link di email.
+func (h *AuthHandler) ConfirmEmailVerification(w http.ResponseWriter, r *http.Request) {
+	var payload AccountTokenPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.authService.ConfirmEmailVerification(r.Context(), payload.Token); err != nil {
+		writeAccountTokenError(w, err, "verifying email")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email verified successfully"})
+}
+
+// ResetPassword mengganti password memakai token dari link di email. Semua sesi lama dicabut;
+// user login ulang dengan password baru.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload AccountTokenPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), payload.Token, payload.NewPassword); err != nil {
+		writeAccountTokenError(w, err, "resetting password")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset successfully"})
+}
+
+func writeAccountTokenError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAccountToken):
+		writeJSONError(w, http.StatusBadRequest, "Invalid or expired token")
+	case errors.Is(err, service.ErrPasswordRequired):
+		writeJSONError(w, http.StatusBadRequest, "new_password is required")
+	default:
+		log.Printf("ERROR %s: %v", action, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to process token")
+	}
+}
+
+type UpdateLocalePayload struct {
+	Locale string `json:"locale"`
+}
+
+// UpdateLocale mengubah bahasa yang dipakai AI untuk roadmap, tugas, dan feedback user.
+func (h *AuthHandler) UpdateLocale(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var payload UpdateLocalePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.authService.UpdateLocale(r.Context(), userID, payload.Locale); err != nil {
+		if errors.Is(err, service.ErrUnsupportedLocale) {
+			writeJSONError(w, http.StatusBadRequest, "Unsupported locale. Supported: "+strings.Join(service.SupportedLocales, ", "))
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to update locale")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Locale updated successfully", "locale": payload.Locale})
+}
@@ -2,10 +2,14 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/auth"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5"
@@ -17,7 +21,9 @@ type GoalHandler struct {
 
 // Payload untuk request pembuatan goal
 type CreateGoalPayload struct {
-	Description string `json:"description"`
+	Description string  `json:"description"`
+	Priority    int     `json:"priority"`    // Opsional, 1-10 (default 1)
+	TargetDate  *string `json:"target_date"` // Opsional, format YYYY-MM-DD
 }
 
 type UpdateGoalPayload struct {
@@ -25,7 +31,9 @@ type UpdateGoalPayload struct {
 }
 
 type AddStepPayload struct {
-    Title string `json:"title"`
+    Title       string  `json:"title"`
+    ParentID    *string `json:"parent_id"`    // Opsional, untuk menambah sub-langkah
+    IsMilestone bool    `json:"is_milestone"` // Opsional
 }
 
 type ReorderPayload struct {
@@ -36,6 +44,26 @@ type UpdateStepStatusPayload struct {
 	Status string `json:"status"`
 }
 
+type UpdateGoalPriorityPayload struct {
+	Priority int `json:"priority"`
+}
+
+type UpdateGoalTargetDatePayload struct {
+	TargetDate *string `json:"target_date"` // null untuk menghapus target tanggal
+}
+
+type ChangeGoalStatusPayload struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+type RegeneratePayload struct {
+	Mode         string                   `json:"mode"`         // split atau rephrase (hanya untuk satu langkah)
+	Instructions string                   `json:"instructions"` // Opsional, catatan tambahan untuk AI
+	Preview      bool                     `json:"preview"`      // true: hanya kembalikan usulan
+	Steps        []repository.RoadmapStep `json:"steps"`        // Opsional, usulan dari preview untuk disimpan tanpa memanggil AI
+}
+
 func NewGoalHandler(goalService *service.GoalService) *GoalHandler {
 	return &GoalHandler{goalService: goalService}
 }
@@ -55,12 +83,24 @@ func (h *GoalHandler) CreateGoal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	targetDate, err := parseTargetDate(payload.TargetDate)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "target_date must use the YYYY-MM-DD format")
+		return
+	}
+
 	// 3. Panggil service untuk melakukan semua logika
-	goal, steps, err := h.goalService.CreateNewGoal(r.Context(), userID, payload.Description)
+	goal, steps, err := h.goalService.CreateNewGoal(r.Context(), userID, payload.Description, payload.Priority, targetDate)
     if err != nil {
+        if writeGoalError(w, err) {
+            return
+        }
         // --- PERBAIKAN LOGGING DI SINI ---
         // Kita log error aslinya ke terminal server untuk debugging
         log.Printf("ERROR creating goal with AI: %v", err) 
+        if writeAIError(w, err) {
+            return
+        }
         // Kirim pesan error yang lebih umum ke frontend
         writeJSONError(w, http.StatusInternalServerError, "Failed to generate roadmap from AI.")
         return
@@ -97,12 +137,13 @@ func (h *GoalHandler) GetActiveGoal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 4. Kirim response sukses dengan data yang ditemukan
+	// 4. Kirim response sukses dengan data yang ditemukan (pacing null jika goal tanpa target tanggal)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"goal":  goal,
-		"steps": steps,
+		"goal":   goal,
+		"steps":  steps,
+		"pacing": h.goalService.Pacing(goal, steps),
 	})
 }
 
@@ -120,6 +161,9 @@ func (h *GoalHandler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 
     goal, steps, err := h.goalService.UpdateGoal(r.Context(), userID, goalID, payload.Description)
     if err != nil {
+        if writeAIError(w, err) || writeGoalError(w, err) {
+            return
+        }
         writeJSONError(w, http.StatusInternalServerError, "Failed to update goal")
         return
     }
@@ -133,6 +177,11 @@ func (h *GoalHandler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *GoalHandler) AddRoadmapStep(w http.ResponseWriter, r *http.Request) {
+    userID, ok := r.Context().Value(auth.UserIDKey).(string)
+    if !ok {
+        writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+        return
+    }
     goalID := chi.URLParam(r, "goalId")
 
     var payload AddStepPayload
@@ -145,8 +194,16 @@ func (h *GoalHandler) AddRoadmapStep(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    newStep, err := h.goalService.AddRoadmapStep(r.Context(), goalID, payload.Title)
+    newStep, err := h.goalService.AddRoadmapStep(r.Context(), userID, goalID, payload.Title, payload.ParentID, payload.IsMilestone)
     if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            writeJSONError(w, http.StatusNotFound, "Goal not found")
+            return
+        }
+        if errors.Is(err, service.ErrInvalidParentStep) {
+            writeJSONError(w, http.StatusBadRequest, "parent_id must be a top-level step of the same goal")
+            return
+        }
         writeJSONError(w, http.StatusInternalServerError, "Failed to add roadmap step")
         return
     }
@@ -218,6 +275,10 @@ func (h *GoalHandler) ReorderRoadmapSteps(w http.ResponseWriter, r *http.Request
 	}
 
 	if err := h.goalService.ReorderRoadmapSteps(r.Context(), userID, payload.StepIDs); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeJSONError(w, http.StatusBadRequest, "All steps must exist and share the same goal and parent")
+			return
+		}
 		writeJSONError(w, http.StatusInternalServerError, "Failed to reorder steps")
 		return
 	}
@@ -246,9 +307,343 @@ func (h *GoalHandler) UpdateRoadmapStepStatus(w http.ResponseWriter, r *http.Req
 			writeJSONError(w, http.StatusNotFound, "Roadmap step not found or permission denied")
 			return
 		}
+		if errors.Is(err, service.ErrInvalidStepStatus) {
+			writeJSONError(w, http.StatusBadRequest, "Status must be one of pending, in_progress, completed, skipped")
+			return
+		}
+		if writeTransitionError(w, err) {
+			return
+		}
 		writeJSONError(w, http.StatusInternalServerError, "Failed to update step status")
 		return
 	}
 
 	writeJSONError(w, http.StatusOK, "Roadmap step status updated")
-}
\ No newline at end of file
+}
+
+// GetRoadmapStepHistory mengembalikan riwayat perubahan status sebuah langkah roadmap.
+func (h *GoalHandler) GetRoadmapStepHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	history, err := h.goalService.GetStepHistory(r.Context(), userID, chi.URLParam(r, "stepId"))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			writeJSONError(w, http.StatusNotFound, "Roadmap step not found")
+			return
+		}
+		log.Printf("ERROR getting step history: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get step history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+// RegenerateRoadmapStep membuat ulang satu langkah roadmap dengan AI (split atau rephrase).
+func (h *GoalHandler) RegenerateRoadmapStep(w http.ResponseWriter, r *http.Request) {
+	h.regenerate(w, r, func(userID string, req service.RegenerateRequest) (*service.RegenerationResult, error) {
+		return h.goalService.RegenerateStep(r.Context(), userID, chi.URLParam(r, "stepId"), req)
+	})
+}
+
+// RegenerateRoadmapTail membuat ulang langkah roadmap yang belum dikerjakan.
+func (h *GoalHandler) RegenerateRoadmapTail(w http.ResponseWriter, r *http.Request) {
+	h.regenerate(w, r, func(userID string, req service.RegenerateRequest) (*service.RegenerationResult, error) {
+		return h.goalService.RegenerateRoadmapTail(r.Context(), userID, chi.URLParam(r, "goalId"), req)
+	})
+}
+
+func (h *GoalHandler) regenerate(w http.ResponseWriter, r *http.Request, run func(userID string, req service.RegenerateRequest) (*service.RegenerationResult, error)) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var payload RegeneratePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := run(userID, service.RegenerateRequest{
+		Mode:         payload.Mode,
+		Instructions: strings.TrimSpace(payload.Instructions),
+		Preview:      payload.Preview,
+		Proposal:     payload.Steps,
+	})
+	if err != nil {
+		var proposalErr *service.ProposalError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			writeJSONError(w, http.StatusNotFound, "Roadmap not found or permission denied")
+		case errors.Is(err, service.ErrInvalidRegenerateMode):
+			writeJSONError(w, http.StatusBadRequest, "Mode must be one of split, rephrase")
+		case errors.Is(err, service.ErrStepResolved):
+			writeJSONError(w, http.StatusConflict, "Completed or skipped steps cannot be regenerated")
+		case errors.As(err, &proposalErr):
+			writeJSONError(w, http.StatusBadRequest, proposalErr.Error())
+		default:
+			log.Printf("ERROR regenerating roadmap: %v", err)
+			if writeAIError(w, err) {
+				return
+			}
+			writeJSONError(w, http.StatusInternalServerError, "Failed to regenerate roadmap")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListGoals mengembalikan goal milik user. Query opsional: ?status=completed,archived
+// (?active=true tetap didukung sebagai alias ?status=active).
+func (h *GoalHandler) ListGoals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var statuses []string
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				statuses = append(statuses, status)
+			}
+		}
+	} else if r.URL.Query().Get("active") == "true" {
+		statuses = []string{"active"}
+	}
+
+	goals, err := h.goalService.ListGoals(r.Context(), userID, statuses)
+	if err != nil {
+		if writeGoalError(w, err) {
+			return
+		}
+		log.Printf("ERROR listing goals: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list goals")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(goals)
+}
+
+// GetGoal mengembalikan satu goal (aktif atau tidak) beserta roadmap-nya.
+func (h *GoalHandler) GetGoal(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	goal, steps, err := h.goalService.GetGoal(r.Context(), userID, chi.URLParam(r, "goalId"))
+	if err != nil {
+		if writeGoalError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get goal")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"goal":   goal,
+		"steps":  steps,
+		"pacing": h.goalService.Pacing(goal, steps),
+	})
+}
+
+func (h *GoalHandler) ActivateGoal(w http.ResponseWriter, r *http.Request) {
+	h.setGoalActive(w, r, true)
+}
+
+func (h *GoalHandler) DeactivateGoal(w http.ResponseWriter, r *http.Request) {
+	h.setGoalActive(w, r, false)
+}
+
+func (h *GoalHandler) setGoalActive(w http.ResponseWriter, r *http.Request, active bool) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	goal, err := h.goalService.SetGoalActive(r.Context(), userID, chi.URLParam(r, "goalId"), active)
+	if err != nil {
+		if writeGoalError(w, err) {
+			return
+		}
+		log.Printf("ERROR changing goal activation: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to update goal")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(goal)
+}
+
+// UpdateGoalPriority mengubah bobot goal untuk pembagian tugas harian.
+func (h *GoalHandler) UpdateGoalPriority(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var payload UpdateGoalPriorityPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	goal, err := h.goalService.UpdateGoalPriority(r.Context(), userID, chi.URLParam(r, "goalId"), payload.Priority)
+	if err != nil {
+		if writeGoalError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to update goal priority")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(goal)
+}
+
+// UpdateGoalTargetDate mengubah target tanggal goal dan menjadwalkan ulang due_date langkah yang belum selesai.
+func (h *GoalHandler) UpdateGoalTargetDate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var payload UpdateGoalTargetDatePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	targetDate, err := parseTargetDate(payload.TargetDate)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "target_date must use the YYYY-MM-DD format")
+		return
+	}
+
+	goal, steps, err := h.goalService.UpdateGoalTargetDate(r.Context(), userID, chi.URLParam(r, "goalId"), targetDate)
+	if err != nil {
+		if writeGoalError(w, err) {
+			return
+		}
+		log.Printf("ERROR updating goal target date: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to update goal target date")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"goal":   goal,
+		"steps":  steps,
+		"pacing": h.goalService.Pacing(goal, steps),
+	})
+}
+
+// ChangeGoalStatus memindahkan goal ke status lain (active, paused, completed, abandoned, archived).
+func (h *GoalHandler) ChangeGoalStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var payload ChangeGoalStatusPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	goal, err := h.goalService.ChangeGoalStatus(r.Context(), userID, chi.URLParam(r, "goalId"), payload.Status, payload.Note)
+	if err != nil {
+		if writeGoalError(w, err) {
+			return
+		}
+		log.Printf("ERROR changing goal status: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to update goal status")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(goal)
+}
+
+// GetGoalHistory mengembalikan riwayat perubahan status sebuah goal.
+func (h *GoalHandler) GetGoalHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	history, err := h.goalService.GetGoalHistory(r.Context(), userID, chi.URLParam(r, "goalId"))
+	if err != nil {
+		if writeGoalError(w, err) {
+			return
+		}
+		log.Printf("ERROR getting goal history: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get goal history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+// writeGoalError memetakan error validasi goal ke status HTTP. Mengembalikan false
+// jika err tidak dikenali.
+func writeGoalError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeJSONError(w, http.StatusNotFound, "Goal not found")
+	case errors.Is(err, service.ErrDescriptionEmpty):
+		writeJSONError(w, http.StatusBadRequest, "Description cannot be empty")
+	case errors.Is(err, service.ErrInvalidPriority):
+		writeJSONError(w, http.StatusBadRequest, "Priority must be between 1 and 10")
+	case errors.Is(err, service.ErrActiveGoalLimit):
+		writeJSONError(w, http.StatusConflict, "Active goal limit reached, deactivate another goal first")
+	case errors.Is(err, service.ErrInvalidTargetDate):
+		writeJSONError(w, http.StatusBadRequest, "target_date must be after today")
+	case errors.Is(err, service.ErrInvalidGoalStatus):
+		writeJSONError(w, http.StatusBadRequest, "Status must be one of active, paused, completed, abandoned, archived")
+	default:
+		return writeTransitionError(w, err)
+	}
+	return true
+}
+
+// parseTargetDate mem-parsing tanggal opsional berformat YYYY-MM-DD. nil atau string kosong
+// berarti tanpa target tanggal.
+func parseTargetDate(value *string) (*time.Time, error) {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(*value))
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
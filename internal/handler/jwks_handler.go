@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/auth"
+)
+
+type JWKSHandler struct {
+	keys *auth.KeySet
+}
+
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS mempublikasikan kunci publik penandatangan access token agar layanan lain bisa
+// memverifikasi token Momentum sendiri. Kunci dipilih berdasarkan kid di header token.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// Cache singkat: kunci baru dipublikasikan sebelum waktu aktifnya, jadi klien tidak ketinggalan
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]auth.JWK{"keys": h.keys.JWKS()})
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/auth"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/service"
+)
+
+type StatsHandler struct {
+	streakService *service.StreakService
+}
+
+func NewStatsHandler(streakService *service.StreakService) *StatsHandler {
+	return &StatsHandler{streakService: streakService}
+}
+
+// GetStreak mengembalikan streak saat ini, rekor terpanjang, sisa freeze, dan riwayat 30 hari.
+func (h *StatsHandler) GetStreak(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	stats, err := h.streakService.GetStreak(r.Context(), userID, time.Now().UTC())
+	if err != nil {
+		log.Printf("ERROR getting streak: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get streak")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
+}
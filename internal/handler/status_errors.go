@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/service"
+)
+
+// writeTransitionError memetakan perubahan status yang tidak diizinkan (goal, langkah
+// roadmap, atau tugas) ke 422 Unprocessable Entity. Mengembalikan false jika err bukan
+// error transisi.
+func writeTransitionError(w http.ResponseWriter, err error) bool {
+	var goalErr *service.GoalTransitionError
+	var statusErr *service.StatusTransitionError
+	switch {
+	case errors.As(err, &goalErr):
+		writeJSONError(w, http.StatusUnprocessableEntity, goalErr.Error())
+	case errors.As(err, &statusErr):
+		writeJSONError(w, http.StatusUnprocessableEntity, statusErr.Error())
+	default:
+		return false
+	}
+	return true
+}
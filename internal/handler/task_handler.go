@@ -2,6 +2,8 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 
 	// Ganti dengan path modul Anda
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/auth"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/service"
 )
 
@@ -47,6 +50,9 @@ func (h *TaskHandler) StartDay(w http.ResponseWriter, r *http.Request) {
 	}
 	tasks, err := h.taskService.StartNewDay(r.Context(), userID)
 	if err != nil {
+		if writeAIError(w, err) {
+			return
+		}
 		writeJSONError(w, http.StatusInternalServerError, "Failed to start new day")
 		return
 	}
@@ -108,19 +114,78 @@ func (h *TaskHandler) UpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.taskService.UpdateTaskStatus(r.Context(), userID, taskID, payload.Status)
+	stepProgress, err := h.taskService.UpdateTaskStatus(r.Context(), userID, taskID, payload.Status)
     if err != nil {
         // --- LOGIKA ERROR BARU ---
-        if err == pgx.ErrNoRows {
+        if errors.Is(err, pgx.ErrNoRows) {
             http.Error(w, "Task not found or user does not have permission", http.StatusNotFound) // 404 Not Found
             return
         }
+        if errors.Is(err, service.ErrInvalidTaskStatus) {
+            writeJSONError(w, http.StatusBadRequest, "Status must be one of pending, completed, missed")
+            return
+        }
+        if writeTransitionError(w, err) {
+            return
+        }
         http.Error(w, "Failed to update task status", http.StatusInternalServerError)
         return
     }
 
+    // step_progress null untuk tugas manual yang tidak terhubung ke langkah roadmap
     w.WriteHeader(http.StatusOK)
-    json.NewEncoder(w).Encode(map[string]string{"message": "Task status updated"})
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "message":       "Task status updated",
+        "step_progress": stepProgress,
+    })
+}
+
+// GetTaskHistory mengembalikan riwayat perubahan status sebuah tugas.
+func (h *TaskHandler) GetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	history, err := h.taskService.GetTaskHistory(r.Context(), userID, chi.URLParam(r, "taskId"))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeJSONError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		log.Printf("ERROR getting task history: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get task history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+// GetStepProgress mengembalikan progres langkah roadmap yang dihitung dari tugas-tugasnya.
+func (h *TaskHandler) GetStepProgress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	progress, err := h.taskService.GetStepProgress(r.Context(), userID, chi.URLParam(r, "stepId"))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeJSONError(w, http.StatusNotFound, "Roadmap step not found")
+			return
+		}
+		log.Printf("ERROR getting step progress: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get step progress")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(progress)
 }
 
 func (h *TaskHandler) UpdateTaskDeadline(w http.ResponseWriter, r *http.Request) {
@@ -145,7 +210,7 @@ func (h *TaskHandler) UpdateTaskDeadline(w http.ResponseWriter, r *http.Request)
 	err := h.taskService.UpdateTaskDeadline(r.Context(), userID, taskID, payload.Deadline)
 	if err != nil {
         // --- LOGIKA ERROR BARU ---
-        if err == pgx.ErrNoRows {
+        if errors.Is(err, pgx.ErrNoRows) {
             http.Error(w, "Task not found or user does not have permission", http.StatusNotFound) // 404 Not Found
             return
         }
@@ -175,7 +240,7 @@ func (h *TaskHandler) UpdateTaskTitle(w http.ResponseWriter, r *http.Request) {
 	err := h.taskService.UpdateTaskTitle(r.Context(), userID, taskID, payload.Title)
 	if err != nil {
         // --- LOGIKA ERROR BARU ---
-        if err == pgx.ErrNoRows {
+        if errors.Is(err, pgx.ErrNoRows) {
             http.Error(w, "Task not found or user does not have permission", http.StatusNotFound) // 404 Not Found
             return
         }
@@ -227,7 +292,7 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	err := h.taskService.DeleteTask(r.Context(), userID, taskID)
 	if err != nil {
 		// Jika errornya karena task tidak ditemukan
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			http.Error(w, "Task not found or user does not have permission", http.StatusNotFound)
 			return
 		}
@@ -261,6 +326,47 @@ func (h *TaskHandler) ReviewDay(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ReviewDayStream adalah varian SSE dari ReviewDay. Event yang dikirim:
+// "summary" (ringkasan tugas), "token" ({"text": ...}) untuk setiap potongan feedback,
+// lalu "done" berisi summary dan feedback lengkap, atau "error" jika gagal. Jika AI gagal
+// setelah sebagian token terkirim, "reset" dikirim sebelum "done" agar klien membuang token
+// tersebut; "done" lalu berisi feedback default.
+func (h *TaskHandler) ReviewDayStream(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	sse, ok := newSSEWriter(w)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	summary, feedback, err := h.taskService.StreamDayReview(r.Context(), userID, time.Now().UTC(), &service.ReviewStream{
+		OnSummary: func(summary []repository.TaskSummary) error {
+			return sse.send("summary", summary)
+		},
+		OnChunk: func(chunk string) error {
+			return sse.send("token", map[string]string{"text": chunk})
+		},
+		OnReset: func() error {
+			return sse.send("reset", map[string]string{"reason": "AI feedback failed, discard received tokens"})
+		},
+	})
+	if err != nil {
+		log.Printf("ERROR streaming day review: %v", err)
+		sse.send("error", map[string]string{"error": "Failed to finalize day review"})
+		return
+	}
+
+	sse.send("done", map[string]interface{}{
+		"summary":     summary,
+		"ai_feedback": feedback,
+	})
+}
+
 // GetHistoryByDate adalah handler untuk fitur riwayat.
 func (h *TaskHandler) GetHistoryByDate(w http.ResponseWriter, r *http.Request) {
 	userID, _ := r.Context().Value(auth.UserIDKey).(string)
@@ -274,7 +380,7 @@ func (h *TaskHandler) GetHistoryByDate(w http.ResponseWriter, r *http.Request) {
 
 	review, err := h.taskService.GetReviewByDate(r.Context(), userID, date)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			writeJSONError(w, http.StatusNotFound, "No review found for this date.")
 			return
 		}
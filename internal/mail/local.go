@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// LocalMailer tidak mengirim email sungguhan. Jika dir diisi, setiap email disimpan sebagai
+// file .eml sehingga alur verifikasi dan reset password bisa dicoba secara offline (link di
+// dalam email bisa disalin langsung). Tanpa dir, email ditulis ke log dengan token di link
+// disamarkan, kecuali logBody diaktifkan.
+type LocalMailer struct {
+	dir     string
+	from    string
+	logBody bool // Tulis isi email apa adanya ke log, termasuk token; hanya untuk lokal
+}
+
+// tokenParam mencocokkan nilai parameter token di link email.
+var tokenParam = regexp.MustCompile(`([?&]token=)[^&\s]+`)
+
+// redactTokens menyamarkan nilai token di link agar log tidak bisa dipakai mengambil alih akun.
+func redactTokens(body string) string {
+	return tokenParam.ReplaceAllString(body, "${1}[disamarkan]")
+}
+
+func NewLocalMailer(dir, from string) *LocalMailer {
+	return &LocalMailer{dir: dir, from: from}
+}
+
+func (m *LocalMailer) Send(ctx context.Context, msg Message) error {
+	data, err := format(m.from, msg)
+	if err != nil {
+		return err
+	}
+	if m.dir == "" {
+		body := msg.Body
+		if !m.logBody {
+			body = redactTokens(body)
+		}
+		log.Printf("EMAIL ke %s: %s\n%s", msg.To, msg.Subject, body)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), hex.EncodeToString(suffix))
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	log.Printf("EMAIL ke %s (%s) disimpan di %s", msg.To, msg.Subject, path)
+	return nil
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocalMailerLogRedactsTokens(t *testing.T) {
+	msg := Message{
+		To:      "budi@example.com",
+		Subject: "Reset password",
+		Body:    "Buka link berikut:\n\nhttp://localhost:3000/reset-password?token=abc123_-XYZ&lang=id\n\nAtau http://localhost:3000/verify-email?token=def456\n",
+	}
+
+	tests := []struct {
+		name      string
+		logBody   bool
+		wantIn    []string
+		wantNotIn []string
+	}{
+		{
+			name:      "tokens are redacted by default",
+			wantIn:    []string{"reset-password?token=[disamarkan]&lang=id", "verify-email?token=[disamarkan]"},
+			wantNotIn: []string{"abc123", "def456"},
+		},
+		{
+			name:    "full body only when explicitly enabled",
+			logBody: true,
+			wantIn:  []string{"token=abc123_-XYZ&lang=id", "token=def456"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			log.SetOutput(&out)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			mailer := NewLocalMailer("", "Momentum <no-reply@momentum.local>")
+			mailer.logBody = tt.logBody
+			if err := mailer.Send(context.Background(), msg); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			logged := out.String()
+			for _, want := range tt.wantIn {
+				if !strings.Contains(logged, want) {
+					t.Errorf("log does not contain %q:\n%s", want, logged)
+				}
+			}
+			for _, leaked := range tt.wantNotIn {
+				if strings.Contains(logged, leaked) {
+					t.Errorf("log leaks %q:\n%s", leaked, logged)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Package mail mengirim email transaksional (verifikasi email, reset password).
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"strings"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
+)
+
+// Message adalah satu email teks biasa.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer mengirim email. Implementasi: SMTPMailer untuk produksi dan LocalMailer untuk
+// pengembangan tanpa server email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailerFromConfig memilih implementasi berdasarkan MAIL_DRIVER (smtp | file | log).
+// MAIL_DRIVER wajib diisi agar produksi tidak diam-diam menulis email ke log. Driver log
+// menyamarkan token di dalam link kecuali MAIL_LOG_BODY=true.
+func NewMailerFromConfig() Mailer {
+	from := config.Get("MAIL_FROM")
+	if from == "" {
+		from = "Momentum <no-reply@momentum.local>"
+	}
+
+	switch driver := strings.ToLower(strings.TrimSpace(config.Get("MAIL_DRIVER"))); driver {
+	case "smtp":
+		host := config.Get("SMTP_HOST")
+		if host == "" {
+			log.Fatal("SMTP_HOST environment variable is not set (set MAIL_DRIVER=log to run offline)")
+		}
+		port := config.Get("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return NewSMTPMailer(host, port, config.Get("SMTP_USERNAME"), config.Get("SMTP_PASSWORD"), from)
+	case "file":
+		dir := config.Get("MAIL_DIR")
+		if dir == "" {
+			dir = "tmp/mail"
+		}
+		log.Printf("MAIL_DRIVER=file: email disimpan di %s", dir)
+		return NewLocalMailer(dir, from)
+	case "log":
+		logBody := config.Get("MAIL_LOG_BODY") == "true"
+		if logBody {
+			log.Println("MAIL_DRIVER=log: email ditulis utuh ke log, termasuk token (MAIL_LOG_BODY=true, hanya untuk lokal)")
+		} else {
+			log.Println("MAIL_DRIVER=log: email ditulis ke log dengan token disamarkan")
+		}
+		mailer := NewLocalMailer("", from)
+		mailer.logBody = logBody
+		return mailer
+	case "":
+		log.Fatal("MAIL_DRIVER environment variable is not set (use smtp, file, or log)")
+		return nil
+	default:
+		log.Fatalf("Unknown MAIL_DRIVER %q (use smtp, file, or log)", driver)
+		return nil
+	}
+}
+
+// format menyusun email lengkap dengan header dalam format RFC 5322. Header yang berisi
+// baris baru ditolak agar alamat atau subjek tidak bisa menyisipkan header lain.
+func format(from string, msg Message) ([]byte, error) {
+	for _, header := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, errors.New("mail: header tidak boleh berisi baris baru")
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Tujuan token akun sekali pakai.
+const (
+	AccountTokenVerifyEmail   = "verify_email"
+	AccountTokenResetPassword = "reset_password"
+)
+
+type AccountTokenRepository struct {
+	db Querier
+}
+
+func NewAccountTokenRepository(db Querier) *AccountTokenRepository {
+	return &AccountTokenRepository{db: db}
+}
+
+// WithTx mengembalikan AccountTokenRepository yang menjalankan query di dalam tx.
+func (r *AccountTokenRepository) WithTx(tx pgx.Tx) *AccountTokenRepository {
+	return &AccountTokenRepository{db: tx}
+}
+
+// CreateAccountToken menyimpan token baru dan sekaligus menandai token lain dengan tujuan
+// yang sama milik user tersebut sebagai terpakai, sehingga hanya link terakhir yang berlaku.
+func (r *AccountTokenRepository) CreateAccountToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time) error {
+	sql := `WITH superseded AS (
+	            UPDATE account_tokens SET used_at = NOW()
+	            WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	        )
+	        INSERT INTO account_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.Exec(ctx, sql, userID, purpose, tokenHash, expiresAt)
+	return err
+}
+
+// LatestAccountTokenAt mengembalikan waktu token terakhir dibuat untuk user dan tujuan
+// tersebut, atau nil jika belum pernah ada.
+func (r *AccountTokenRepository) LatestAccountTokenAt(ctx context.Context, userID, purpose string) (*time.Time, error) {
+	var createdAt *time.Time
+	sql := "SELECT MAX(created_at) FROM account_tokens WHERE user_id = $1 AND purpose = $2"
+	err := r.db.QueryRow(ctx, sql, userID, purpose).Scan(&createdAt)
+	return createdAt, err
+}
+
+// ConsumeAccountToken menandai token terpakai dan mengembalikan ID user pemiliknya.
+// Mengembalikan pgx.ErrNoRows jika token tidak dikenal, sudah dipakai, atau kedaluwarsa.
+func (r *AccountTokenRepository) ConsumeAccountToken(ctx context.Context, purpose, tokenHash string) (string, error) {
+	var userID string
+	sql := `UPDATE account_tokens SET used_at = NOW()
+	        WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+	        RETURNING user_id`
+	err := r.db.QueryRow(ctx, sql, tokenHash, purpose).Scan(&userID)
+	return userID, err
+}
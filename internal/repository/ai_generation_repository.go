@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Hasil dari satu panggilan AI.
+const (
+	GenerationOutcomeOK            = "ok"
+	GenerationOutcomeInvalidOutput = "invalid_output"
+	GenerationOutcomeProviderError = "provider_error"
+)
+
+type AIGeneration struct {
+	ID               string    `json:"id"`
+	UserID           *string   `json:"user_id"`
+	GoalID           *string   `json:"goal_id"`
+	Kind             string    `json:"kind"`
+	Provider         string    `json:"provider"`
+	Attempt          int       `json:"attempt"`
+	PromptVersion    string    `json:"prompt_version"`
+	Locale           string    `json:"locale"`
+	Prompt           string    `json:"prompt"`
+	RawResponse      *string   `json:"raw_response"`
+	CleanedJSON      *string   `json:"cleaned_json"`
+	Outcome          string    `json:"outcome"`
+	ErrorMessage     *string   `json:"error_message"`
+	LatencyMs        int       `json:"latency_ms"`
+	PromptTokens     *int      `json:"prompt_tokens"`
+	CompletionTokens *int      `json:"completion_tokens"`
+	TotalTokens      *int      `json:"total_tokens"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type AIGenerationRepository struct {
+	db Querier
+}
+
+func NewAIGenerationRepository(db Querier) *AIGenerationRepository {
+	return &AIGenerationRepository{db: db}
+}
+
+// CreateGeneration menyimpan satu catatan panggilan AI.
+func (r *AIGenerationRepository) CreateGeneration(ctx context.Context, g *AIGeneration) error {
+	sql := `INSERT INTO ai_generations (user_id, goal_id, kind, provider, attempt, prompt_version, locale, prompt, raw_response, cleaned_json,
+	            outcome, error_message, latency_ms, prompt_tokens, completion_tokens, total_tokens)
+	        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+	_, err := r.db.Exec(ctx, sql, g.UserID, g.GoalID, g.Kind, g.Provider, g.Attempt, g.PromptVersion, g.Locale, g.Prompt, g.RawResponse, g.CleanedJSON,
+		g.Outcome, g.ErrorMessage, g.LatencyMs, g.PromptTokens, g.CompletionTokens, g.TotalTokens)
+	return err
+}
+
+// ListGenerationsByUserID mengambil riwayat panggilan AI milik user, terbaru lebih dulu.
+func (r *AIGenerationRepository) ListGenerationsByUserID(ctx context.Context, userID string, limit, offset int) ([]AIGeneration, error) {
+	generations := []AIGeneration{}
+	sql := `SELECT id, user_id, goal_id, kind, provider, attempt, COALESCE(prompt_version, ''), COALESCE(locale, ''), prompt, raw_response, cleaned_json,
+	               outcome, error_message, latency_ms, prompt_tokens, completion_tokens, total_tokens, created_at
+	        FROM ai_generations
+	        WHERE user_id = $1
+	        ORDER BY created_at DESC
+	        LIMIT $2 OFFSET $3`
+	rows, err := r.db.Query(ctx, sql, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var g AIGeneration
+		if err := rows.Scan(&g.ID, &g.UserID, &g.GoalID, &g.Kind, &g.Provider, &g.Attempt, &g.PromptVersion, &g.Locale, &g.Prompt, &g.RawResponse, &g.CleanedJSON,
+			&g.Outcome, &g.ErrorMessage, &g.LatencyMs, &g.PromptTokens, &g.CompletionTokens, &g.TotalTokens, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		generations = append(generations, g)
+	}
+	return generations, rows.Err()
+}
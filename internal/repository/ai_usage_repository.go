@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// AIUsage adalah jumlah generasi AI yang sudah dipakai user.
+type AIUsage struct {
+	DailyUsed   int
+	MonthlyUsed int
+}
+
+// usageCountsSQL menghitung pemakaian pada hari $2 dan sepanjang bulan kalender $2.
+const usageCountsSQL = `SELECT
+	    COALESCE(SUM(generation_count) FILTER (WHERE usage_date = $2::date), 0),
+	    COALESCE(SUM(generation_count), 0)
+	FROM ai_usage
+	WHERE user_id = $1 AND usage_date >= date_trunc('month', $2::date) AND usage_date <= $2::date`
+
+type AIUsageRepository struct {
+	db Querier
+}
+
+func NewAIUsageRepository(db Querier) *AIUsageRepository {
+	return &AIUsageRepository{db: db}
+}
+
+// GetLimitOverrides mengambil batas kuota khusus untuk user (nil = pakai default).
+func (r *AIUsageRepository) GetLimitOverrides(ctx context.Context, userID string) (daily *int, monthly *int, err error) {
+	sql := "SELECT ai_daily_limit, ai_monthly_limit FROM users WHERE id = $1"
+	err = r.db.QueryRow(ctx, sql, userID).Scan(&daily, &monthly)
+	return daily, monthly, err
+}
+
+// GetUsage mengambil pemakaian pada hari `day` dan pada bulan kalender yang sama.
+func (r *AIUsageRepository) GetUsage(ctx context.Context, userID string, day time.Time) (*AIUsage, error) {
+	var usage AIUsage
+	err := r.db.QueryRow(ctx, usageCountsSQL, userID, day).Scan(&usage.DailyUsed, &usage.MonthlyUsed)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// ConsumeGeneration menambah pemakaian satu generasi jika masih di bawah batas.
+// Batas <= 0 berarti tidak dibatasi. Pemeriksaan dan penambahan dilakukan atomik
+// per user dengan advisory lock, sehingga request paralel tidak bisa melewati kuota.
+func (r *AIUsageRepository) ConsumeGeneration(ctx context.Context, userID string, day time.Time, dailyLimit, monthlyLimit int) (*AIUsage, bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('ai_usage:' || $1))", userID); err != nil {
+		return nil, false, err
+	}
+
+	var usage AIUsage
+	if err := tx.QueryRow(ctx, usageCountsSQL, userID, day).Scan(&usage.DailyUsed, &usage.MonthlyUsed); err != nil {
+		return nil, false, err
+	}
+
+	if (dailyLimit > 0 && usage.DailyUsed >= dailyLimit) || (monthlyLimit > 0 && usage.MonthlyUsed >= monthlyLimit) {
+		return &usage, false, nil
+	}
+
+	upsert := `INSERT INTO ai_usage (user_id, usage_date, generation_count) VALUES ($1, $2, 1)
+	           ON CONFLICT (user_id, usage_date) DO UPDATE SET generation_count = ai_usage.generation_count + 1`
+	if _, err := tx.Exec(ctx, upsert, userID, day); err != nil {
+		return nil, false, err
+	}
+	usage.DailyUsed++
+	usage.MonthlyUsed++
+
+	return &usage, true, tx.Commit(ctx)
+}
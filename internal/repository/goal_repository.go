@@ -2,47 +2,261 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Asal konten roadmap dan tugas.
+const (
+	SourceAI       = "ai"       // Dihasilkan oleh AI
+	SourceTemplate = "template" // Template cadangan saat AI tidak tersedia
+	SourceManual   = "manual"   // Dibuat langsung oleh pengguna
+)
+
+// Status siklus hidup goal. Hanya goal "active" yang mendapat tugas harian.
+const (
+	GoalStatusActive    = "active"
+	GoalStatusPaused    = "paused"
+	GoalStatusCompleted = "completed"
+	GoalStatusAbandoned = "abandoned"
+	GoalStatusArchived  = "archived"
 )
 
 type Goal struct {
-	ID          string `json:"id"`
-	UserID      string `json:"user_id"`
-	Description string `json:"description"`
-	IsActive    bool   `json:"is_active"`
+	ID            string `json:"id"`
+	UserID        string `json:"user_id"`
+	Description   string `json:"description"`
+	IsActive      bool   `json:"is_active"` // Diturunkan dari Status (kolom generated)
+	Status        string `json:"status"`
+	RoadmapSource string `json:"roadmap_source"`
+	Priority      int    `json:"priority"` // Bobot 1-10 untuk pembagian tugas harian antar goal aktif
+
+	TargetDate *time.Time `json:"target_date"` // Opsional, hanya bagian tanggal yang dipakai
+
+	CreatedAt       time.Time  `json:"created_at"`
+	StatusChangedAt time.Time  `json:"status_changed_at"`
+	CompletedAt     *time.Time `json:"completed_at"`
+	AbandonedAt     *time.Time `json:"abandoned_at"`
+	ArchivedAt      *time.Time `json:"archived_at"`
+}
+
+// GoalStatusChange adalah satu baris riwayat status goal.
+type GoalStatusChange struct {
+	FromStatus *string   `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Note       *string   `json:"note"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// Batas bobot goal (lihat CHECK constraint di tabel goals).
+const (
+	MinGoalPriority = 1
+	MaxGoalPriority = 10
+)
+
+const goalColumns = "id, user_id, description, is_active, status, roadmap_source, priority, target_date, created_at, status_changed_at, completed_at, abandoned_at, archived_at"
+
+// goalOrder mengurutkan goal aktif dengan bobot tertinggi lebih dulu, lalu yang paling lama.
+const goalOrder = "ORDER BY is_active DESC, priority DESC, created_at ASC"
+
+func scanGoal(row pgx.Row, goal *Goal) error {
+	return row.Scan(&goal.ID, &goal.UserID, &goal.Description, &goal.IsActive, &goal.Status, &goal.RoadmapSource, &goal.Priority, &goal.TargetDate,
+		&goal.CreatedAt, &goal.StatusChangedAt, &goal.CompletedAt, &goal.AbandonedAt, &goal.ArchivedAt)
 }
 
 type GoalRepository struct {
-	db *pgxpool.Pool
+	db Querier
 }
 
-func NewGoalRepository(db *pgxpool.Pool) *GoalRepository {
+func NewGoalRepository(db Querier) *GoalRepository {
 	return &GoalRepository{db: db}
 }
 
-// CreateGoal menyimpan goal baru ke database dan mengembalikan ID-nya.
+// WithTx mengembalikan GoalRepository yang menjalankan query di dalam tx.
+func (r *GoalRepository) WithTx(tx pgx.Tx) *GoalRepository {
+	return &GoalRepository{db: tx}
+}
+
+// CreateGoal menyimpan goal baru ke database (beserta baris pertama riwayat statusnya)
+// dan mengembalikan ID-nya.
 func (r *GoalRepository) CreateGoal(ctx context.Context, goal *Goal) (string, error) {
+	if goal.Priority == 0 {
+		goal.Priority = MinGoalPriority
+	}
+	if goal.Status == "" {
+		goal.Status = GoalStatusActive
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
 	var id string
-	sql := "INSERT INTO goals (user_id, description, is_active) VALUES ($1, $2, $3) RETURNING id"
-	err := r.db.QueryRow(ctx, sql, goal.UserID, goal.Description, goal.IsActive).Scan(&id)
+	sql := `INSERT INTO goals (user_id, description, status, roadmap_source, priority, target_date) VALUES ($1, $2, $3, $4, $5, $6)
+	        RETURNING id, is_active, created_at, status_changed_at`
+	err = tx.QueryRow(ctx, sql, goal.UserID, goal.Description, goal.Status, goal.RoadmapSource, goal.Priority, goal.TargetDate).
+		Scan(&id, &goal.IsActive, &goal.CreatedAt, &goal.StatusChangedAt)
 	if err != nil {
 		return "", err
 	}
-	return id, nil
+	history := "INSERT INTO goal_status_history (goal_id, from_status, to_status) VALUES ($1, NULL, $2)"
+	if _, err := tx.Exec(ctx, history, id, goal.Status); err != nil {
+		return "", err
+	}
+	return id, tx.Commit(ctx)
 }
 
+// GetActiveGoalByUserID mengambil goal aktif utama (bobot tertinggi) milik user.
 func (r *GoalRepository) GetActiveGoalByUserID(ctx context.Context, userID string) (*Goal, error) {
 	var goal Goal
-	sql := "SELECT id, user_id, description, is_active FROM goals WHERE user_id = $1 AND is_active = TRUE LIMIT 1"
-	err := r.db.QueryRow(ctx, sql, userID).Scan(&goal.ID, &goal.UserID, &goal.Description, &goal.IsActive)
+	sql := "SELECT " + goalColumns + " FROM goals WHERE user_id = $1 AND is_active = TRUE " + goalOrder + " LIMIT 1"
+	err := scanGoal(r.db.QueryRow(ctx, sql, userID), &goal)
 	if err != nil {
 		return nil, err // Akan mengembalikan error jika tidak ada baris yang ditemukan
 	}
 	return &goal, nil
 }
 
+// GetActiveGoalsByUserID mengambil semua goal aktif milik user, bobot tertinggi lebih dulu.
+func (r *GoalRepository) GetActiveGoalsByUserID(ctx context.Context, userID string) ([]Goal, error) {
+	return r.queryGoals(ctx, "SELECT "+goalColumns+" FROM goals WHERE user_id = $1 AND is_active = TRUE "+goalOrder, userID)
+}
+
+// ListGoalsByUserID mengambil goal milik user (aktif lebih dulu). Jika statuses tidak
+// kosong, hanya goal dengan status tersebut yang dikembalikan.
+func (r *GoalRepository) ListGoalsByUserID(ctx context.Context, userID string, statuses []string) ([]Goal, error) {
+	if len(statuses) == 0 {
+		return r.queryGoals(ctx, "SELECT "+goalColumns+" FROM goals WHERE user_id = $1 "+goalOrder, userID)
+	}
+	return r.queryGoals(ctx, "SELECT "+goalColumns+" FROM goals WHERE user_id = $1 AND status = ANY($2) "+goalOrder, userID, statuses)
+}
+
+func (r *GoalRepository) queryGoals(ctx context.Context, sql string, args ...any) ([]Goal, error) {
+	goals := []Goal{}
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var goal Goal
+		if err := scanGoal(rows, &goal); err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+// GetGoalByID mengambil satu goal milik user. Mengembalikan pgx.ErrNoRows jika goal
+// tidak ada atau milik user lain.
+func (r *GoalRepository) GetGoalByID(ctx context.Context, userID, goalID string) (*Goal, error) {
+	var goal Goal
+	sql := "SELECT " + goalColumns + " FROM goals WHERE id = $1 AND user_id = $2"
+	if err := scanGoal(r.db.QueryRow(ctx, sql, goalID, userID), &goal); err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+// CountActiveGoals menghitung jumlah goal aktif milik user.
+func (r *GoalRepository) CountActiveGoals(ctx context.Context, userID string) (int, error) {
+	var count int
+	sql := "SELECT COUNT(*) FROM goals WHERE user_id = $1 AND is_active = TRUE"
+	err := r.db.QueryRow(ctx, sql, userID).Scan(&count)
+	return count, err
+}
+
+// LockActiveGoals mengambil advisory lock transaksi untuk goal aktif user, sehingga pemeriksaan
+// batas goal aktif dan penyimpanannya dari request paralel berjalan bergantian. Harus dipanggil
+// di dalam transaksi (lihat WithTx).
+func (r *GoalRepository) LockActiveGoals(ctx context.Context, userID string) error {
+	_, err := r.db.Exec(ctx, "SELECT pg_advisory_xact_lock(3, hashtext($1))", userID)
+	return err
+}
+
+// UpdateGoalStatus memindahkan goal dari status `from` ke `to` dan mencatatnya di riwayat.
+// Timestamp status terkait diisi saat masuk ke status tersebut dan dikosongkan saat goal
+// dibuka kembali. Mengembalikan pgx.ErrNoRows jika goal tidak ditemukan atau statusnya
+// sudah berubah sejak dibaca.
+func (r *GoalRepository) UpdateGoalStatus(ctx context.Context, userID, goalID, from, to, note string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE goals SET
+	            status = $1,
+	            status_changed_at = NOW(),
+	            completed_at = CASE WHEN $1 = 'completed' THEN NOW() WHEN $1 IN ('active', 'paused') THEN NULL ELSE completed_at END,
+	            abandoned_at = CASE WHEN $1 = 'abandoned' THEN NOW() WHEN $1 IN ('active', 'paused') THEN NULL ELSE abandoned_at END,
+	            archived_at = CASE WHEN $1 = 'archived' THEN NOW() ELSE NULL END
+	        WHERE id = $2 AND user_id = $3 AND status = $4`
+	result, err := tx.Exec(ctx, sql, to, goalID, userID, from)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	history := "INSERT INTO goal_status_history (goal_id, from_status, to_status, note) VALUES ($1, $2, $3, NULLIF($4, ''))"
+	if _, err := tx.Exec(ctx, history, goalID, from, to, note); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetGoalStatusHistory mengambil riwayat status goal, terbaru lebih dulu.
+func (r *GoalRepository) GetGoalStatusHistory(ctx context.Context, goalID string) ([]GoalStatusChange, error) {
+	changes := []GoalStatusChange{}
+	sql := `SELECT from_status, to_status, note, changed_at FROM goal_status_history
+	        WHERE goal_id = $1 ORDER BY changed_at DESC`
+	rows, err := r.db.Query(ctx, sql, goalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var change GoalStatusChange
+		if err := rows.Scan(&change.FromStatus, &change.ToStatus, &change.Note, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// UpdateGoalPriority mengubah bobot goal milik user.
+func (r *GoalRepository) UpdateGoalPriority(ctx context.Context, userID, goalID string, priority int) error {
+	sql := "UPDATE goals SET priority = $1 WHERE id = $2 AND user_id = $3"
+	result, err := r.db.Exec(ctx, sql, priority, goalID, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateGoalTargetDate mengubah (atau menghapus, jika nil) target tanggal goal.
+func (r *GoalRepository) UpdateGoalTargetDate(ctx context.Context, userID, goalID string, targetDate *time.Time) error {
+	sql := "UPDATE goals SET target_date = $1 WHERE id = $2 AND user_id = $3"
+	result, err := r.db.Exec(ctx, sql, targetDate, goalID, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
 // UpdateGoalDescription memperbarui kolom deskripsi dari sebuah goal.
 func (r *GoalRepository) UpdateGoalDescription(ctx context.Context, userID, goalID, newDescription string) error {
     sql := "UPDATE goals SET description = $1 WHERE id = $2 AND user_id = $3"
@@ -54,4 +268,11 @@ func (r *GoalRepository) UpdateGoalDescription(ctx context.Context, userID, goal
         return pgx.ErrNoRows
     }
     return nil
-}
\ No newline at end of file
+}
+
+// UpdateRoadmapSource mencatat asal roadmap terbaru dari sebuah goal.
+func (r *GoalRepository) UpdateRoadmapSource(ctx context.Context, goalID, source string) error {
+	sql := "UPDATE goals SET roadmap_source = $1 WHERE id = $2"
+	_, err := r.db.Exec(ctx, sql, source, goalID)
+	return err
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LoginAttempt adalah satu percobaan login.
+type LoginAttempt struct {
+	ID        string    `json:"id"`
+	UserID    *string   `json:"-"`
+	Email     string    `json:"-"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Succeeded bool      `json:"succeeded"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginFailureStats merangkum login gagal terbaru untuk satu email dan satu IP.
+type LoginFailureStats struct {
+	EmailFailures    int // Gagal berturut-turut sejak login sukses terakhir untuk email ini
+	EmailLastFailure *time.Time
+	IPFailures       int // Semua login gagal dari IP ini, untuk email apa pun
+	IPLastFailure    *time.Time
+}
+
+type LoginAttemptRepository struct {
+	db Querier
+}
+
+func NewLoginAttemptRepository(db Querier) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// WithTx mengembalikan LoginAttemptRepository yang menjalankan query di dalam tx.
+func (r *LoginAttemptRepository) WithTx(tx pgx.Tx) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: tx}
+}
+
+// LockLoginAttempts mengambil advisory lock transaksi untuk email lalu IP tersebut, sehingga
+// percobaan login paralel ke akun atau dari IP yang sama membaca statistik dan mencatat
+// percobaannya secara bergantian. Harus dipanggil di dalam transaksi (lihat WithTx).
+// Email dan IP memakai namespace lock berbeda dan selalu dikunci dengan urutan yang sama.
+func (r *LoginAttemptRepository) LockLoginAttempts(ctx context.Context, email, ipAddress string) error {
+	if _, err := r.db.Exec(ctx, "SELECT pg_advisory_xact_lock(1, hashtext($1))", email); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(ctx, "SELECT pg_advisory_xact_lock(2, hashtext($1))", ipAddress)
+	return err
+}
+
+// CreateLoginAttempt mencatat satu percobaan login dan mengisi attempt.ID.
+func (r *LoginAttemptRepository) CreateLoginAttempt(ctx context.Context, attempt *LoginAttempt) error {
+	sql := `INSERT INTO login_attempts (user_id, email, ip_address, user_agent, succeeded)
+	        VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+	        RETURNING id, created_at`
+	return r.db.QueryRow(ctx, sql, attempt.UserID, attempt.Email, attempt.IPAddress, attempt.UserAgent, attempt.Succeeded).
+		Scan(&attempt.ID, &attempt.CreatedAt)
+}
+
+// FinishLoginAttempt mengisi hasil akhir percobaan yang sudah dicatat lebih dulu.
+func (r *LoginAttemptRepository) FinishLoginAttempt(ctx context.Context, attempt *LoginAttempt) error {
+	sql := "UPDATE login_attempts SET user_id = $2, succeeded = $3 WHERE id = $1"
+	_, err := r.db.Exec(ctx, sql, attempt.ID, attempt.UserID, attempt.Succeeded)
+	return err
+}
+
+// GetLoginFailureStats menghitung login gagal sejak waktu since untuk email dan IP tersebut.
+func (r *LoginAttemptRepository) GetLoginFailureStats(ctx context.Context, email, ipAddress string, since time.Time) (*LoginFailureStats, error) {
+	var stats LoginFailureStats
+	sql := `WITH last_success AS (
+	            SELECT COALESCE(MAX(created_at), '-infinity'::timestamptz) AS at
+	            FROM login_attempts WHERE email = $1 AND succeeded
+	        )
+	        SELECT
+	            COUNT(*) FILTER (WHERE email = $1 AND created_at > (SELECT at FROM last_success)),
+	            MAX(created_at) FILTER (WHERE email = $1),
+	            COUNT(*) FILTER (WHERE ip_address = $2),
+	            MAX(created_at) FILTER (WHERE ip_address = $2)
+	        FROM login_attempts
+	        WHERE NOT succeeded AND created_at > $3 AND (email = $1 OR ip_address = $2)`
+	err := r.db.QueryRow(ctx, sql, email, ipAddress, since).
+		Scan(&stats.EmailFailures, &stats.EmailLastFailure, &stats.IPFailures, &stats.IPLastFailure)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// ListFailedLoginsByUserID mengambil login gagal ke akun user, terbaru lebih dulu.
+func (r *LoginAttemptRepository) ListFailedLoginsByUserID(ctx context.Context, userID string, limit, offset int) ([]LoginAttempt, error) {
+	attempts := []LoginAttempt{}
+	sql := `SELECT id, user_id, email, ip_address, COALESCE(user_agent, ''), succeeded, created_at
+	        FROM login_attempts
+	        WHERE user_id = $1 AND NOT succeeded
+	        ORDER BY created_at DESC
+	        LIMIT $2 OFFSET $3`
+	rows, err := r.db.Query(ctx, sql, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a LoginAttempt
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Email, &a.IPAddress, &a.UserAgent, &a.Succeeded, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier adalah operasi database yang dimiliki *pgxpool.Pool maupun pgx.Tx, sehingga
+// repository yang sama bisa dipakai langsung ke pool atau di dalam transaksi (lihat WithTx).
+// Begin di dalam transaksi membuat savepoint, jadi method yang membuka transaksinya sendiri
+// tetap aman dipanggil dari transaksi luar.
+type Querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RefreshToken adalah satu refresh token yang pernah diterbitkan (tanpa token aslinya).
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	FamilyID  string
+	TokenHash string
+	UserAgent string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+type RefreshTokenRepository struct {
+	db Querier
+}
+
+func NewRefreshTokenRepository(db Querier) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// WithTx mengembalikan RefreshTokenRepository yang menjalankan query di dalam tx.
+func (r *RefreshTokenRepository) WithTx(tx pgx.Tx) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: tx}
+}
+
+// CreateRefreshToken menyimpan token baru. FamilyID kosong berarti token memulai family baru.
+func (r *RefreshTokenRepository) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	sql := `INSERT INTO refresh_tokens (user_id, family_id, token_hash, user_agent, expires_at)
+	        VALUES ($1, COALESCE(NULLIF($2, '')::uuid, gen_random_uuid()), $3, NULLIF($4, ''), $5)
+	        RETURNING id, family_id, created_at`
+	return r.db.QueryRow(ctx, sql, token.UserID, token.FamilyID, token.TokenHash, token.UserAgent, token.ExpiresAt).
+		Scan(&token.ID, &token.FamilyID, &token.CreatedAt)
+}
+
+// GetRefreshTokenByHash mengambil token berdasarkan hash-nya, termasuk yang sudah dipakai
+// atau dicabut (dibutuhkan untuk mendeteksi pemakaian ulang).
+func (r *RefreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	var token RefreshToken
+	var userAgent *string
+	sql := `SELECT id, user_id, family_id, token_hash, user_agent, created_at, expires_at, used_at, revoked_at
+	        FROM refresh_tokens WHERE token_hash = $1`
+	err := r.db.QueryRow(ctx, sql, tokenHash).Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &userAgent,
+		&token.CreatedAt, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if userAgent != nil {
+		token.UserAgent = *userAgent
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken menandai token lama terpakai dan menyimpan penggantinya dalam family
+// yang sama, dalam satu transaksi. Mengembalikan pgx.ErrNoRows jika token lama sudah
+// dipakai atau dicabut lebih dulu (misalnya oleh request paralel).
+func (r *RefreshTokenRepository) RotateRefreshToken(ctx context.Context, oldID string, next *RefreshToken) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := "UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL"
+	result, err := tx.Exec(ctx, sql, oldID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if err := (&RefreshTokenRepository{db: tx}).CreateRefreshToken(ctx, next); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RevokeFamily mencabut semua token yang masih berlaku dalam satu family (satu sesi login).
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	sql := "UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL"
+	_, err := r.db.Exec(ctx, sql, familyID)
+	return err
+}
+
+// RevokeUserTokens mencabut semua refresh token user (logout dari semua perangkat).
+func (r *RefreshTokenRepository) RevokeUserTokens(ctx context.Context, userID string) error {
+	sql := "UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL"
+	_, err := r.db.Exec(ctx, sql, userID)
+	return err
+}
@@ -5,8 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"time"
-
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Struct ini sekarang dengan json tag yang eksplisit
@@ -18,10 +16,10 @@ type DailyReview struct {
 }
 
 type ReviewRepository struct {
-	db *pgxpool.Pool
+	db Querier
 }
 
-func NewReviewRepository(db *pgxpool.Pool) *ReviewRepository {
+func NewReviewRepository(db Querier) *ReviewRepository {
 	return &ReviewRepository{db: db}
 }
 
@@ -56,4 +54,28 @@ func (r *ReviewRepository) GetReviewByDate(ctx context.Context, userID string, r
 		return nil, err
 	}
 	return &review, nil
-}
\ No newline at end of file
+}
+
+// GetLatestReviewBefore mengambil review terakhir user sebelum tanggal tertentu.
+func (r *ReviewRepository) GetLatestReviewBefore(ctx context.Context, userID string, before time.Time) (*DailyReview, error) {
+	var review DailyReview
+	var summaryJSON []byte
+	sql := `SELECT user_id, review_date, summary_json, COALESCE(ai_feedback_text, '')
+	        FROM daily_reviews
+	        WHERE user_id = $1 AND review_date < DATE($2)
+	        ORDER BY review_date DESC
+	        LIMIT 1`
+	err := r.db.QueryRow(ctx, sql, userID, before).Scan(
+		&review.UserID,
+		&review.ReviewDate,
+		&summaryJSON,
+		&review.AIFeedback,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(summaryJSON, &review.Summary); err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
@@ -3,9 +3,17 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Status langkah roadmap (lihat CHECK constraint roadmap_steps_status_check).
+const (
+	StepStatusPending    = "pending"
+	StepStatusInProgress = "in_progress"
+	StepStatusCompleted  = "completed"
+	StepStatusSkipped    = "skipped" // Dilewati user; dianggap tuntas seperti completed
 )
 
 type RoadmapStep struct {
@@ -14,37 +22,78 @@ type RoadmapStep struct {
 	Order   int    `json:"step_order"`
 	Title   string `json:"title"`
 	Status  string `json:"status"`
+
+	EstimatedDays *int       `json:"estimated_days"` // Estimasi durasi dari AI, opsional
+	DueDate       *time.Time `json:"due_date"`       // Dihitung dari estimasi dan target tanggal goal
+
+	ParentID    *string       `json:"parent_id"`          // nil untuk langkah utama
+	IsMilestone bool          `json:"is_milestone"`       // Penanda pencapaian penting di roadmap
+	Children    []RoadmapStep `json:"children,omitempty"` // Sub-langkah, hanya diisi pada bentuk pohon
+}
+
+const stepColumns = "id, goal_id, step_order, title, status, estimated_days, due_date, parent_id, is_milestone"
+
+func scanStep(row pgx.Row, step *RoadmapStep) error {
+	return row.Scan(&step.ID, &step.GoalID, &step.Order, &step.Title, &step.Status, &step.EstimatedDays, &step.DueDate,
+		&step.ParentID, &step.IsMilestone)
 }
 
 type RoadmapRepository struct {
-	db *pgxpool.Pool
+	db Querier
 }
 
-func NewRoadmapRepository(db *pgxpool.Pool) *RoadmapRepository {
+func NewRoadmapRepository(db Querier) *RoadmapRepository {
 	return &RoadmapRepository{db: db}
 }
 
-// CreateRoadmapSteps memasukkan beberapa langkah roadmap sekaligus.
+// WithTx mengembalikan RoadmapRepository yang menjalankan query di dalam tx.
+func (r *RoadmapRepository) WithTx(tx pgx.Tx) *RoadmapRepository {
+	return &RoadmapRepository{db: tx}
+}
+
+// CreateRoadmapSteps memasukkan beberapa langkah roadmap beserta sub-langkahnya (Children)
+// dalam satu transaksi. ID dan ParentID hasil insert diisi kembali ke steps.
 func (r *RoadmapRepository) CreateRoadmapSteps(ctx context.Context, steps []RoadmapStep) error {
-	// Kita akan menggunakan fitur CopyFrom dari pgx untuk bulk insert yang efisien.
-	rows := make([][]interface{}, len(steps))
-	for i, step := range steps {
-		rows[i] = []interface{}{step.GoalID, step.Order, step.Title, step.Status}
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertSteps(ctx, tx, steps, nil); err != nil {
+		return err
 	}
+	return tx.Commit(ctx)
+}
 
-	_, err := r.db.CopyFrom(
-		ctx,
-		pgx.Identifier{"roadmap_steps"},
-		[]string{"goal_id", "step_order", "title", "status"},
-		pgx.CopyFromRows(rows),
-	)
+func insertSteps(ctx context.Context, tx pgx.Tx, steps []RoadmapStep, parentID *string) error {
+	sql := `INSERT INTO roadmap_steps (goal_id, step_order, title, status, estimated_days, due_date, parent_id, is_milestone)
+	        VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	for i := range steps {
+		step := &steps[i]
+		step.ParentID = parentID
+		if step.Status == "" {
+			step.Status = StepStatusPending
+		}
+		err := tx.QueryRow(ctx, sql, step.GoalID, step.Order, step.Title, step.Status, step.EstimatedDays, step.DueDate,
+			step.ParentID, step.IsMilestone).Scan(&step.ID)
+		if err != nil {
+			return err
+		}
 
-	return err
+		for j := range step.Children {
+			step.Children[j].GoalID = step.GoalID
+		}
+		if err := insertSteps(ctx, tx, step.Children, &step.ID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *RoadmapRepository) GetRoadmapStepsByGoalID(ctx context.Context, goalID string) ([]RoadmapStep, error) {
 	var steps []RoadmapStep
-	sql := "SELECT id, goal_id, step_order, title, status FROM roadmap_steps WHERE goal_id = $1 ORDER BY step_order ASC"
+	sql := "SELECT " + stepColumns + " FROM roadmap_steps WHERE goal_id = $1 ORDER BY step_order ASC"
 	rows, err := r.db.Query(ctx, sql, goalID)
 	if err != nil {
 		return nil, err
@@ -53,7 +102,7 @@ func (r *RoadmapRepository) GetRoadmapStepsByGoalID(ctx context.Context, goalID
 
 	for rows.Next() {
 		var step RoadmapStep
-		if err := rows.Scan(&step.ID, &step.GoalID, &step.Order, &step.Title, &step.Status); err != nil {
+		if err := scanStep(rows, &step); err != nil {
 			return nil, err
 		}
 		steps = append(steps, step)
@@ -69,10 +118,12 @@ func (r *RoadmapRepository) DeleteRoadmapStepsByGoalID(ctx context.Context, goal
     return err
 }
 
-func (r *RoadmapRepository) GetLastStepOrder(ctx context.Context, goalID string) (int, error) {
+// GetLastStepOrder mengambil urutan terakhir di antara langkah dengan parent yang sama
+// (parentID nil untuk langkah utama).
+func (r *RoadmapRepository) GetLastStepOrder(ctx context.Context, goalID string, parentID *string) (int, error) {
     var lastOrder int
-    sql := "SELECT COALESCE(MAX(step_order), 0) FROM roadmap_steps WHERE goal_id = $1"
-    err := r.db.QueryRow(ctx, sql, goalID).Scan(&lastOrder)
+    sql := "SELECT COALESCE(MAX(step_order), 0) FROM roadmap_steps WHERE goal_id = $1 AND parent_id IS NOT DISTINCT FROM $2"
+    err := r.db.QueryRow(ctx, sql, goalID, parentID).Scan(&lastOrder)
     if err != nil {
         return 0, err
     }
@@ -82,17 +133,12 @@ func (r *RoadmapRepository) GetLastStepOrder(ctx context.Context, goalID string)
 // CreateRoadmapStep menyimpan satu langkah roadmap baru.
 func (r *RoadmapRepository) CreateRoadmapStep(ctx context.Context, step *RoadmapStep) (*RoadmapStep, error) {
     var createdStep RoadmapStep
-    sql := `INSERT INTO roadmap_steps (goal_id, step_order, title, status)
-            VALUES ($1, $2, $3, $4)
-            RETURNING id, goal_id, step_order, title, status`
-
-    err := r.db.QueryRow(ctx, sql, step.GoalID, step.Order, step.Title, step.Status).Scan(
-        &createdStep.ID,
-        &createdStep.GoalID,
-        &createdStep.Order,
-        &createdStep.Title,
-        &createdStep.Status,
-    )
+    sql := `INSERT INTO roadmap_steps (goal_id, step_order, title, status, estimated_days, due_date, parent_id, is_milestone)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+            RETURNING ` + stepColumns
+
+    err := scanStep(r.db.QueryRow(ctx, sql, step.GoalID, step.Order, step.Title, step.Status, step.EstimatedDays, step.DueDate,
+        step.ParentID, step.IsMilestone), &createdStep)
     if err != nil {
         return nil, err
     }
@@ -117,10 +163,11 @@ func (r *RoadmapRepository) UpdateStepTitle(ctx context.Context, userID, stepID,
 	return nil
 }
 
-// Ubah fungsi DeleteRoadmapStep menjadi seperti ini
-func (r *RoadmapRepository) DeleteRoadmapStep(ctx context.Context, tx pgx.Tx, stepID string) error {
+// DeleteRoadmapStep menghapus satu langkah. Gunakan bersama RenumberStepsAfterDelete di dalam
+// transaksi yang sama (lihat WithTx).
+func (r *RoadmapRepository) DeleteRoadmapStep(ctx context.Context, stepID string) error {
     sql := `DELETE FROM roadmap_steps WHERE id = $1`
-    result, err := tx.Exec(ctx, sql, stepID)
+    result, err := r.db.Exec(ctx, sql, stepID)
     if err != nil {
         return err
     }
@@ -139,10 +186,23 @@ func (r *RoadmapRepository) ReorderRoadmapSteps(ctx context.Context, userID stri
 	// 2. Pastikan transaksi di-rollback jika ada error di tengah jalan
 	defer tx.Rollback(ctx)
 
+	// Semua langkah harus berasal dari satu goal milik user dan punya parent yang sama,
+	// karena step_order hanya berlaku di antara saudara satu parent
+	var goalCount, parentCount, stepCount int
+	check := `SELECT COUNT(DISTINCT rs.goal_id), COUNT(DISTINCT COALESCE(rs.parent_id::text, '')), COUNT(*)
+	          FROM roadmap_steps rs JOIN goals g ON g.id = rs.goal_id
+	          WHERE rs.id = ANY($1) AND g.user_id = $2`
+	if err := tx.QueryRow(ctx, check, stepIDs, userID).Scan(&goalCount, &parentCount, &stepCount); err != nil {
+		return err
+	}
+	if goalCount != 1 || parentCount != 1 || stepCount != len(stepIDs) {
+		return pgx.ErrNoRows
+	}
+
 	// Query untuk update satu langkah, dengan validasi kepemilikan
 	sql := `UPDATE roadmap_steps SET step_order = $1
-	        WHERE id = $2 AND goal_id = (
-	            SELECT id FROM goals WHERE user_id = $3 AND is_active = TRUE
+	        WHERE id = $2 AND goal_id IN (
+	            SELECT id FROM goals WHERE user_id = $3
 	        )`
 
 	// 3. Lakukan update satu per satu untuk setiap langkah
@@ -161,43 +221,60 @@ func (r *RoadmapRepository) ReorderRoadmapSteps(ctx context.Context, userID stri
 
 func (r *RoadmapRepository) GetStepByID(ctx context.Context, stepID string) (*RoadmapStep, error) {
     var step RoadmapStep
-    sql := "SELECT id, goal_id, step_order FROM roadmap_steps WHERE id = $1"
-    err := r.db.QueryRow(ctx, sql, stepID).Scan(&step.ID, &step.GoalID, &step.Order)
+    sql := "SELECT " + stepColumns + " FROM roadmap_steps WHERE id = $1"
+    err := scanStep(r.db.QueryRow(ctx, sql, stepID), &step)
     if err != nil {
         return nil, err
     }
     return &step, nil
 }
 
-// RenumberStepsAfterDelete (untuk merapikan urutan)
-func (r *RoadmapRepository) RenumberStepsAfterDelete(ctx context.Context, tx pgx.Tx, goalID string, deletedOrder int) error {
-    sql := "UPDATE roadmap_steps SET step_order = step_order - 1 WHERE goal_id = $1 AND step_order > $2"
-    _, err := tx.Exec(ctx, sql, goalID, deletedOrder)
+// RenumberStepsAfterDelete (untuk merapikan urutan di antara saudara satu parent)
+func (r *RoadmapRepository) RenumberStepsAfterDelete(ctx context.Context, goalID string, parentID *string, deletedOrder int) error {
+    sql := "UPDATE roadmap_steps SET step_order = step_order - 1 WHERE goal_id = $1 AND parent_id IS NOT DISTINCT FROM $3 AND step_order > $2"
+    _, err := r.db.Exec(ctx, sql, goalID, deletedOrder, parentID)
     return err
 }
 
+// GetNextPendingStep mengambil langkah terdepan yang belum tuntas (pending atau in_progress)
+// dan bisa langsung dikerjakan: sub-langkah pertama yang belum tuntas, atau langkah utama
+// itu sendiri jika tidak punya sub-langkah yang tersisa.
 func (r *RoadmapRepository) GetNextPendingStep(ctx context.Context, goalID string) (*RoadmapStep, error) {
     var step RoadmapStep
-    sql := `SELECT id, goal_id, step_order, title, status 
-            FROM roadmap_steps 
-            WHERE goal_id = $1 AND status = 'pending' 
-            ORDER BY step_order ASC 
+    sql := `SELECT s.id, s.goal_id, s.step_order, s.title, s.status, s.estimated_days, s.due_date, s.parent_id, s.is_milestone
+            FROM roadmap_steps s
+            LEFT JOIN roadmap_steps p ON p.id = s.parent_id
+            WHERE s.goal_id = $1 AND s.status IN ('pending', 'in_progress')
+              AND (p.id IS NULL OR p.status IN ('pending', 'in_progress'))
+              AND NOT EXISTS (
+                  SELECT 1 FROM roadmap_steps c
+                  WHERE c.parent_id = s.id AND c.status IN ('pending', 'in_progress')
+              )
+            ORDER BY COALESCE(p.step_order, s.step_order) ASC, (s.parent_id IS NULL) ASC, s.step_order ASC
             LIMIT 1`
 
-    err := r.db.QueryRow(ctx, sql, goalID).Scan(&step.ID, &step.GoalID, &step.Order, &step.Title, &step.Status)
+    err := scanStep(r.db.QueryRow(ctx, sql, goalID), &step)
     if err != nil {
         return nil, err 
     }
     return &step, nil
 }
 
-func (r *RoadmapRepository) UpdateStepStatus(ctx context.Context, userID, stepID, status string) error {
-	sql := `UPDATE roadmap_steps SET status = $1 
-	        WHERE id = $2 AND goal_id = (
-	            SELECT id FROM goals WHERE user_id = $3 AND is_active = TRUE
-	        )`
+// UpdateStepStatus memindahkan langkah dari status `from` ke `to` dan mencatatnya di riwayat.
+// Mengembalikan pgx.ErrNoRows jika langkah tidak ditemukan, bukan milik user, atau statusnya
+// sudah berubah sejak dibaca.
+func (r *RoadmapRepository) UpdateStepStatus(ctx context.Context, userID, stepID, from, to string) error {
+	sql := `WITH updated AS (
+	            UPDATE roadmap_steps SET status = $1
+	            WHERE id = $2 AND status = $4 AND goal_id IN (
+	                SELECT id FROM goals WHERE user_id = $3
+	            )
+	            RETURNING id
+	        )
+	        INSERT INTO roadmap_step_status_history (step_id, from_status, to_status)
+	        SELECT id, $4, $1 FROM updated`
 
-	result, err := r.db.Exec(ctx, sql, status, stepID, userID)
+	result, err := r.db.Exec(ctx, sql, to, stepID, userID, from)
 	if err != nil {
 		return err
 	}
@@ -205,4 +282,185 @@ func (r *RoadmapRepository) UpdateStepStatus(ctx context.Context, userID, stepID
 		return pgx.ErrNoRows
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// MarkStepInProgress menandai langkah pending sebagai in_progress. Langkah dengan status
+// lain tidak diubah.
+func (r *RoadmapRepository) MarkStepInProgress(ctx context.Context, stepID string) error {
+	sql := `WITH updated AS (
+	            UPDATE roadmap_steps SET status = 'in_progress' WHERE id = $1 AND status = 'pending'
+	            RETURNING id
+	        )
+	        INSERT INTO roadmap_step_status_history (step_id, from_status, to_status)
+	        SELECT id, 'pending', 'in_progress' FROM updated`
+	_, err := r.db.Exec(ctx, sql, stepID)
+	return err
+}
+
+// AllChildrenResolved memeriksa apakah langkah punya sub-langkah dan semuanya sudah tuntas.
+func (r *RoadmapRepository) AllChildrenResolved(ctx context.Context, parentID string) (bool, error) {
+	var done bool
+	sql := `SELECT COUNT(*) > 0 AND COUNT(*) FILTER (WHERE status IN ('pending', 'in_progress')) = 0
+	        FROM roadmap_steps WHERE parent_id = $1`
+	err := r.db.QueryRow(ctx, sql, parentID).Scan(&done)
+	return done, err
+}
+
+// UpdateStepDueDates menyimpan due_date hasil penjadwalan ulang untuk beberapa langkah sekaligus.
+func (r *RoadmapRepository) UpdateStepDueDates(ctx context.Context, steps []RoadmapStep) error {
+	batch := &pgx.Batch{}
+	for _, step := range steps {
+		batch.Queue("UPDATE roadmap_steps SET due_date = $1 WHERE id = $2", step.DueDate, step.ID)
+	}
+	return r.db.SendBatch(ctx, batch).Close()
+}
+
+// AllStepsCompleted memeriksa apakah goal punya langkah roadmap dan semua langkah utamanya
+// sudah tuntas (completed atau skipped).
+func (r *RoadmapRepository) AllStepsCompleted(ctx context.Context, goalID string) (bool, error) {
+	var done bool
+	sql := `SELECT COUNT(*) > 0 AND COUNT(*) FILTER (WHERE status IN ('pending', 'in_progress')) = 0
+	        FROM roadmap_steps WHERE goal_id = $1 AND parent_id IS NULL`
+	err := r.db.QueryRow(ctx, sql, goalID).Scan(&done)
+	return done, err
+}
+
+// GetStepStatusHistory mengambil riwayat status langkah roadmap, terbaru lebih dulu.
+func (r *RoadmapRepository) GetStepStatusHistory(ctx context.Context, stepID string) ([]StatusChange, error) {
+	sql := `SELECT from_status, to_status, changed_at FROM roadmap_step_status_history
+	        WHERE step_id = $1 ORDER BY changed_at DESC`
+	return queryStatusHistory(ctx, r.db, sql, stepID)
+}
+
+// ReplaceChildSteps mengganti sub-langkah parent yang masih pending dengan children dalam satu
+// transaksi. Sub-langkah yang sudah dikerjakan dipertahankan di depan; children ditambahkan setelahnya.
+func (r *RoadmapRepository) ReplaceChildSteps(ctx context.Context, goalID, parentID string, children []RoadmapStep) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := "DELETE FROM roadmap_steps WHERE parent_id = $1 AND status = 'pending'"
+	if _, err := tx.Exec(ctx, sql, parentID); err != nil {
+		return err
+	}
+	kept, err := compactStepOrder(ctx, tx, goalID, &parentID)
+	if err != nil {
+		return err
+	}
+	for i := range children {
+		children[i].GoalID = goalID
+		children[i].Order = kept + i + 1
+	}
+	if err := insertSteps(ctx, tx, children, &parentID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ReplaceStep mengganti satu langkah dengan beberapa langkah baru di posisi yang sama (di antara
+// saudara satu parent). Tugas dan riwayat status langkah lama dipindahkan ke langkah baru pertama.
+func (r *RoadmapRepository) ReplaceStep(ctx context.Context, step *RoadmapStep, replacements []RoadmapStep) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Geser saudara setelahnya agar ada tempat untuk langkah pengganti
+	shift := `UPDATE roadmap_steps SET step_order = step_order + $1
+	          WHERE goal_id = $2 AND parent_id IS NOT DISTINCT FROM $3 AND step_order > $4`
+	if _, err := tx.Exec(ctx, shift, len(replacements)-1, step.GoalID, step.ParentID, step.Order); err != nil {
+		return err
+	}
+	for i := range replacements {
+		replacements[i].GoalID = step.GoalID
+		replacements[i].Order = step.Order + i
+	}
+	if err := insertSteps(ctx, tx, replacements, step.ParentID); err != nil {
+		return err
+	}
+
+	relink := "UPDATE tasks SET roadmap_step_id = $1 WHERE roadmap_step_id = $2"
+	if _, err := tx.Exec(ctx, relink, replacements[0].ID, step.ID); err != nil {
+		return err
+	}
+	moveHistory := "UPDATE roadmap_step_status_history SET step_id = $1 WHERE step_id = $2"
+	moved, err := tx.Exec(ctx, moveHistory, replacements[0].ID, step.ID)
+	if err != nil {
+		return err
+	}
+	// Langkah baru pertama mewarisi status langkah lama; catat jika riwayatnya belum menjelaskan itu
+	if moved.RowsAffected() == 0 && replacements[0].Status != StepStatusPending {
+		history := `INSERT INTO roadmap_step_status_history (step_id, from_status, to_status)
+		            VALUES ($1, 'pending', $2)`
+		if _, err := tx.Exec(ctx, history, replacements[0].ID, replacements[0].Status); err != nil {
+			return err
+		}
+	}
+	if err := r.WithTx(tx).DeleteRoadmapStep(ctx, step.ID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ReplaceRoadmapTail menghapus langkah utama tailIDs (beserta sub-langkahnya; tugas yang
+// terhubung tetap ada tanpa langkah), merapikan urutan langkah yang tersisa, lalu menambahkan
+// steps di akhir roadmap. Semua dalam satu transaksi. tailIDs harus langkah terakhir roadmap
+// yang belum dikerjakan sama sekali; langkah pending di depan langkah yang sudah dikerjakan
+// tidak ikut dihapus.
+func (r *RoadmapRepository) ReplaceRoadmapTail(ctx context.Context, goalID string, tailIDs []string, steps []RoadmapStep) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `DELETE FROM roadmap_steps s
+	        WHERE s.goal_id = $1 AND s.parent_id IS NULL AND s.status = 'pending' AND s.id = ANY($2)
+	          AND NOT EXISTS (
+	              SELECT 1 FROM roadmap_steps c WHERE c.parent_id = s.id AND c.status <> 'pending'
+	          )
+	          AND NOT EXISTS (
+	              SELECT 1 FROM roadmap_steps later
+	              WHERE later.goal_id = s.goal_id AND later.parent_id IS NULL
+	                AND later.step_order > s.step_order AND NOT later.id = ANY($2)
+	          )`
+	result, err := tx.Exec(ctx, sql, goalID, tailIDs)
+	if err != nil {
+		return err
+	}
+	// Roadmap berubah sejak dibaca (mis. langkah dihapus atau mulai dikerjakan di request lain)
+	if int(result.RowsAffected()) != len(tailIDs) {
+		return pgx.ErrNoRows
+	}
+	kept, err := compactStepOrder(ctx, tx, goalID, nil)
+	if err != nil {
+		return err
+	}
+	for i := range steps {
+		steps[i].GoalID = goalID
+		steps[i].Order = kept + i + 1
+	}
+	if err := insertSteps(ctx, tx, steps, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// compactStepOrder menomori ulang step_order saudara satu parent menjadi 1..n sesuai urutan
+// sebelumnya, lalu mengembalikan n.
+func compactStepOrder(ctx context.Context, tx pgx.Tx, goalID string, parentID *string) (int, error) {
+	sql := `UPDATE roadmap_steps rs SET step_order = o.new_order
+	        FROM (
+	            SELECT id, ROW_NUMBER() OVER (ORDER BY step_order) AS new_order
+	            FROM roadmap_steps WHERE goal_id = $1 AND parent_id IS NOT DISTINCT FROM $2
+	        ) o
+	        WHERE rs.id = o.id`
+	result, err := tx.Exec(ctx, sql, goalID, parentID)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// StatusChange adalah satu baris riwayat status langkah roadmap atau tugas.
+type StatusChange struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// queryStatusHistory menjalankan sql yang mengembalikan (from_status, to_status, changed_at).
+func queryStatusHistory(ctx context.Context, db Querier, sql string, args ...any) ([]StatusChange, error) {
+	changes := []StatusChange{}
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var change StatusChange
+		if err := rows.Scan(&change.FromStatus, &change.ToStatus, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
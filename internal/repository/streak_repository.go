@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Hasil evaluasi streak untuk satu hari.
+const (
+	StreakOutcomeCounted = "counted" // Target penyelesaian tercapai, streak bertambah
+	StreakOutcomeMissed  = "missed"  // Di bawah target
+	StreakOutcomeFrozen  = "frozen"  // Hari terlewat yang ditutup dengan streak freeze
+)
+
+// StreakState adalah kolom streak pada tabel users.
+type StreakState struct {
+	CurrentStreak  int
+	LongestStreak  int
+	LastStreakDate *time.Time // Hari terakhir yang dihitung ke streak
+	Freezes        int        // Jumlah streak freeze yang tersedia
+}
+
+// StreakDay adalah satu baris riwayat streak.
+type StreakDay struct {
+	Date           time.Time `json:"date"`
+	Outcome        string    `json:"outcome"`
+	CompletedTasks int       `json:"completed_tasks"`
+	TotalTasks     int       `json:"total_tasks"`
+}
+
+type StreakRepository struct {
+	db Querier
+}
+
+func NewStreakRepository(db Querier) *StreakRepository {
+	return &StreakRepository{db: db}
+}
+
+// GetStreakState mengambil state streak user.
+func (r *StreakRepository) GetStreakState(ctx context.Context, userID string) (*StreakState, error) {
+	var state StreakState
+	sql := "SELECT current_streak, longest_streak, last_streak_date, streak_freezes FROM users WHERE id = $1"
+	err := r.db.QueryRow(ctx, sql, userID).Scan(&state.CurrentStreak, &state.LongestStreak, &state.LastStreakDate, &state.Freezes)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// UpdateStreak mengunci baris user, memanggil apply untuk mengubah state dan menghasilkan
+// baris riwayat, lalu menyimpan keduanya dalam satu transaksi. Review paralel untuk user
+// yang sama akan menunggu giliran sehingga streak tidak terhitung dua kali.
+func (r *StreakRepository) UpdateStreak(ctx context.Context, userID string, apply func(state *StreakState) []StreakDay) (*StreakState, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var state StreakState
+	sql := "SELECT current_streak, longest_streak, last_streak_date, streak_freezes FROM users WHERE id = $1 FOR UPDATE"
+	if err := tx.QueryRow(ctx, sql, userID).Scan(&state.CurrentStreak, &state.LongestStreak, &state.LastStreakDate, &state.Freezes); err != nil {
+		return nil, err
+	}
+
+	days := apply(&state)
+
+	update := `UPDATE users SET current_streak = $1, longest_streak = $2, last_streak_date = $3, streak_freezes = $4, updated_at = NOW()
+	           WHERE id = $5`
+	if _, err := tx.Exec(ctx, update, state.CurrentStreak, state.LongestStreak, state.LastStreakDate, state.Freezes, userID); err != nil {
+		return nil, err
+	}
+
+	// Hari yang ditutup freeze tetap menyimpan jumlah tugas dari review aslinya (jika ada)
+	upsert := `INSERT INTO streak_days (user_id, streak_date, outcome, completed_tasks, total_tasks)
+	           VALUES ($1, $2, $3, $4, $5)
+	           ON CONFLICT (user_id, streak_date)
+	           DO UPDATE SET outcome = EXCLUDED.outcome,
+	               completed_tasks = CASE WHEN EXCLUDED.outcome = 'frozen' THEN streak_days.completed_tasks ELSE EXCLUDED.completed_tasks END,
+	               total_tasks = CASE WHEN EXCLUDED.outcome = 'frozen' THEN streak_days.total_tasks ELSE EXCLUDED.total_tasks END`
+	for _, day := range days {
+		if _, err := tx.Exec(ctx, upsert, userID, day.Date, day.Outcome, day.CompletedTasks, day.TotalTasks); err != nil {
+			return nil, err
+		}
+	}
+
+	return &state, tx.Commit(ctx)
+}
+
+// GetStreakDays mengambil riwayat streak user sejak tanggal `from`, terbaru lebih dulu.
+func (r *StreakRepository) GetStreakDays(ctx context.Context, userID string, from time.Time) ([]StreakDay, error) {
+	days := []StreakDay{}
+	sql := `SELECT streak_date, outcome, completed_tasks, total_tasks
+	        FROM streak_days
+	        WHERE user_id = $1 AND streak_date >= DATE($2)
+	        ORDER BY streak_date DESC`
+	rows, err := r.db.Query(ctx, sql, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day StreakDay
+		if err := rows.Scan(&day.Date, &day.Outcome, &day.CompletedTasks, &day.TotalTasks); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
@@ -5,7 +5,13 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Status tugas (lihat CHECK constraint tasks_status_check).
+const (
+	TaskStatusPending   = "pending"
+	TaskStatusCompleted = "completed"
+	TaskStatusMissed    = "missed"
 )
 
 // Kita gunakan lagi struct Task yang sudah pernah kita definisikan di ERD
@@ -18,6 +24,7 @@ type Task struct {
 	ScheduledDate time.Time  `json:"scheduled_date"`
 	Deadline      *time.Time `json:"deadline"`       // Pointer agar bisa null
 	CompletedAt   *time.Time `json:"completed_at"`   // Pointer agar bisa null
+	Source        string     `json:"source"`
 }
 
 type TaskSummary struct {
@@ -26,17 +33,22 @@ type TaskSummary struct {
 }
 
 type TaskRepository struct {
-	db *pgxpool.Pool
+	db Querier
 }
 
-func NewTaskRepository(db *pgxpool.Pool) *TaskRepository {
+func NewTaskRepository(db Querier) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
+// WithTx mengembalikan TaskRepository yang menjalankan query di dalam tx.
+func (r *TaskRepository) WithTx(tx pgx.Tx) *TaskRepository {
+	return &TaskRepository{db: tx}
+}
+
 // GetTasksByDate mengambil semua tugas untuk user tertentu pada tanggal tertentu.
 func (r *TaskRepository) GetTasksByDate(ctx context.Context, userID string, date time.Time) ([]Task, error) {
 	var tasks []Task
-	sql := `SELECT id, user_id, roadmap_step_id, title, status, scheduled_date, deadline, completed_at 
+	sql := `SELECT id, user_id, roadmap_step_id, title, status, scheduled_date, deadline, completed_at, source 
 	        FROM tasks 
 	        WHERE user_id = $1 AND DATE(scheduled_date) = DATE($2) 
 	        ORDER BY created_at ASC`
@@ -48,7 +60,7 @@ func (r *TaskRepository) GetTasksByDate(ctx context.Context, userID string, date
 
 	for rows.Next() {
 		var task Task
-		if err := rows.Scan(&task.ID, &task.UserID, &task.RoadmapStepID, &task.Title, &task.Status, &task.ScheduledDate, &task.Deadline, &task.CompletedAt); err != nil {
+		if err := rows.Scan(&task.ID, &task.UserID, &task.RoadmapStepID, &task.Title, &task.Status, &task.ScheduledDate, &task.Deadline, &task.CompletedAt, &task.Source); err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, task)
@@ -59,12 +71,16 @@ func (r *TaskRepository) GetTasksByDate(ctx context.Context, userID string, date
 // CreateTask menyimpan satu tugas baru ke database.
 func (r *TaskRepository) CreateTask(ctx context.Context, task *Task) (*Task, error) {
 	var createdTask Task
-	sql := `INSERT INTO tasks (user_id, roadmap_step_id, title, status, scheduled_date, deadline) 
-	        VALUES ($1, $2, $3, $4, $5, $6) 
-	        RETURNING id, user_id, roadmap_step_id, title, status, scheduled_date, deadline, completed_at`
-	err := r.db.QueryRow(ctx, sql, task.UserID, task.RoadmapStepID, task.Title, task.Status, task.ScheduledDate, task.Deadline).Scan(
+	source := task.Source
+	if source == "" {
+		source = SourceManual
+	}
+	sql := `INSERT INTO tasks (user_id, roadmap_step_id, title, status, scheduled_date, deadline, source) 
+	        VALUES ($1, $2, $3, $4, $5, $6, $7) 
+	        RETURNING id, user_id, roadmap_step_id, title, status, scheduled_date, deadline, completed_at, source`
+	err := r.db.QueryRow(ctx, sql, task.UserID, task.RoadmapStepID, task.Title, task.Status, task.ScheduledDate, task.Deadline, source).Scan(
 		&createdTask.ID, &createdTask.UserID, &createdTask.RoadmapStepID, &createdTask.Title,
-		&createdTask.Status, &createdTask.ScheduledDate, &createdTask.Deadline, &createdTask.CompletedAt,
+		&createdTask.Status, &createdTask.ScheduledDate, &createdTask.Deadline, &createdTask.CompletedAt, &createdTask.Source,
 	)
 	if err != nil {
 		return nil, err
@@ -72,15 +88,23 @@ func (r *TaskRepository) CreateTask(ctx context.Context, task *Task) (*Task, err
 	return &createdTask, nil
 }
 
-// UpdateTaskStatus memperbarui status dan waktu selesai sebuah tugas.
-func (r *TaskRepository) UpdateTaskStatus(ctx context.Context, userID, taskID, status string) error {
+// UpdateTaskStatus memindahkan tugas dari status `from` ke `to`, memperbarui waktu selesai,
+// dan mencatatnya di riwayat. Mengembalikan pgx.ErrNoRows jika tugas tidak ditemukan atau
+// statusnya sudah berubah sejak dibaca.
+func (r *TaskRepository) UpdateTaskStatus(ctx context.Context, userID, taskID, from, to string) error {
 	now := time.Now().UTC()
 	var completedAt *time.Time
-	if status == "completed" {
+	if to == TaskStatusCompleted {
 		completedAt = &now
 	}
-	sql := "UPDATE tasks SET status = $1, completed_at = $2 WHERE id = $3 AND user_id = $4"
-	result, err := r.db.Exec(ctx, sql, status, completedAt, taskID, userID)
+	sql := `WITH updated AS (
+	            UPDATE tasks SET status = $1, completed_at = $2
+	            WHERE id = $3 AND user_id = $4 AND status = $5
+	            RETURNING id
+	        )
+	        INSERT INTO task_status_history (task_id, from_status, to_status)
+	        SELECT id, $5, $1 FROM updated`
+	result, err := r.db.Exec(ctx, sql, to, completedAt, taskID, userID, from)
 	if err != nil { return err }
 	if result.RowsAffected() == 0 { return pgx.ErrNoRows }
 	return nil
@@ -129,8 +153,13 @@ func (r *TaskRepository) DeleteTask(ctx context.Context, userID, taskID string)
 }
 
 func (r *TaskRepository) FinalizeMissedTasks(ctx context.Context, userID string, date time.Time) error {
-	sql := `UPDATE tasks SET status = 'missed' 
-	        WHERE user_id = $1 AND DATE(scheduled_date) = DATE($2) AND status = 'pending' AND deadline < NOW()`
+	sql := `WITH updated AS (
+	            UPDATE tasks SET status = 'missed'
+	            WHERE user_id = $1 AND DATE(scheduled_date) = DATE($2) AND status = 'pending' AND deadline < NOW()
+	            RETURNING id
+	        )
+	        INSERT INTO task_status_history (task_id, from_status, to_status)
+	        SELECT id, 'pending', 'missed' FROM updated`
 	_, err := r.db.Exec(ctx, sql, userID, date)
 	return err
 }
@@ -153,4 +182,66 @@ func (r *TaskRepository) GetTaskSummaryByDate(ctx context.Context, userID string
 		summaries = append(summaries, summary)
 	}
 	return summaries, nil
-}
\ No newline at end of file
+}
+
+// GetTasksBetween mengambil tugas user dengan scheduled_date di rentang [from, to] (inklusif),
+// diurutkan per tanggal lalu waktu pembuatan.
+func (r *TaskRepository) GetTasksBetween(ctx context.Context, userID string, from, to time.Time) ([]Task, error) {
+	var tasks []Task
+	sql := `SELECT id, user_id, roadmap_step_id, title, status, scheduled_date, deadline, completed_at, source
+	        FROM tasks
+	        WHERE user_id = $1 AND scheduled_date BETWEEN DATE($2) AND DATE($3)
+	        ORDER BY scheduled_date ASC, created_at ASC`
+	rows, err := r.db.Query(ctx, sql, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(&task.ID, &task.UserID, &task.RoadmapStepID, &task.Title, &task.Status, &task.ScheduledDate, &task.Deadline, &task.CompletedAt, &task.Source); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// StepTaskProgress adalah jumlah tugas yang terhubung ke sebuah langkah roadmap.
+type StepTaskProgress struct {
+	Completed int `json:"completed"`
+	Total     int `json:"total"`
+}
+
+// GetTaskByID mengambil satu tugas milik user.
+func (r *TaskRepository) GetTaskByID(ctx context.Context, userID, taskID string) (*Task, error) {
+	var task Task
+	sql := `SELECT id, user_id, roadmap_step_id, title, status, scheduled_date, deadline, completed_at, source
+	        FROM tasks WHERE id = $1 AND user_id = $2`
+	err := r.db.QueryRow(ctx, sql, taskID, userID).Scan(&task.ID, &task.UserID, &task.RoadmapStepID, &task.Title, &task.Status,
+		&task.ScheduledDate, &task.Deadline, &task.CompletedAt, &task.Source)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetTaskStatusHistory mengambil riwayat status tugas milik user, terbaru lebih dulu.
+func (r *TaskRepository) GetTaskStatusHistory(ctx context.Context, userID, taskID string) ([]StatusChange, error) {
+	sql := `SELECT h.from_status, h.to_status, h.changed_at
+	        FROM task_status_history h JOIN tasks t ON t.id = h.task_id
+	        WHERE h.task_id = $1 AND t.user_id = $2 ORDER BY h.changed_at DESC`
+	return queryStatusHistory(ctx, r.db, sql, taskID, userID)
+}
+
+// GetStepTaskProgress menghitung tugas selesai vs total untuk sebuah langkah roadmap.
+func (r *TaskRepository) GetStepTaskProgress(ctx context.Context, stepID string) (*StepTaskProgress, error) {
+	var progress StepTaskProgress
+	sql := `SELECT COUNT(*) FILTER (WHERE status = 'completed'), COUNT(*)
+	        FROM tasks WHERE roadmap_step_id = $1`
+	if err := r.db.QueryRow(ctx, sql, stepID).Scan(&progress.Completed, &progress.Total); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
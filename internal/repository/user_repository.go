@@ -2,25 +2,41 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type User struct {
 	ID       string `json:"id"`
 	Email    string `json:"email"`
 	Password string `json:"-"` // Jangan pernah kirim password ke JSON
+	Locale   string `json:"locale"`
+
+	CurrentStreak  int        `json:"current_streak"`
+	LongestStreak  int        `json:"longest_streak"`
+	LastStreakDate *time.Time `json:"last_streak_date"`
+	StreakFreezes  int        `json:"streak_freezes"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	TokenVersion int        `json:"-"` // Dinaikkan untuk mencabut semua access token user
+	LockedAt     *time.Time `json:"-"` // Diisi jika akun dikunci admin
 }
 
 type UserRepository struct {
-	db *pgxpool.Pool
+	db Querier
 }
 
-func NewUserRepository(db *pgxpool.Pool) *UserRepository {
+func NewUserRepository(db Querier) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// WithTx mengembalikan UserRepository yang menjalankan query di dalam tx.
+func (r *UserRepository) WithTx(tx pgx.Tx) *UserRepository {
+	return &UserRepository{db: tx}
+}
+
 func (r *UserRepository) CreateUser(ctx context.Context, user *User) (string, error) {
 	var id string
 	sql := "INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING id"
@@ -35,8 +51,10 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *User) (string, er
 // Penting untuk mengembalikan hash password agar bisa diverifikasi di service.
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	var user User
-	sql := "SELECT id, email, password_hash FROM users WHERE email = $1"
-	err := r.db.QueryRow(ctx, sql, email).Scan(&user.ID, &user.Email, &user.Password)
+	sql := `SELECT id, email, password_hash, locale, token_version, locked_at, email_verified_at
+	        FROM users WHERE email = $1`
+	err := r.db.QueryRow(ctx, sql, email).Scan(&user.ID, &user.Email, &user.Password, &user.Locale,
+		&user.TokenVersion, &user.LockedAt, &user.EmailVerifiedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -45,23 +63,91 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*Use
 
 func (r *UserRepository) GetUserByID(ctx context.Context, userID string) (*User, error) {
     var user User
-    sql := "SELECT id, email, password_hash FROM users WHERE id = $1"
-    err := r.db.QueryRow(ctx, sql, userID).Scan(&user.ID, &user.Email, &user.Password)
+    sql := `SELECT id, email, password_hash, locale, current_streak, longest_streak, last_streak_date, streak_freezes,
+                   email_verified_at
+            FROM users WHERE id = $1`
+    err := r.db.QueryRow(ctx, sql, userID).Scan(&user.ID, &user.Email, &user.Password, &user.Locale,
+        &user.CurrentStreak, &user.LongestStreak, &user.LastStreakDate, &user.StreakFreezes,
+        &user.EmailVerifiedAt)
     if err != nil {
         return nil, err
     }
     return &user, nil
 }
 
-// UpdatePasswordHash hanya mengupdate kolom password_hash.
-func (r *UserRepository) UpdatePasswordHash(ctx context.Context, userID, newHashedPassword string) error {
-    sql := "UPDATE users SET password_hash = $1 WHERE id = $2"
-    result, err := r.db.Exec(ctx, sql, newHashedPassword, userID)
-    if err != nil {
-        return err
-    }
-    if result.RowsAffected() == 0 {
-        return pgx.ErrNoRows
-    }
-    return nil
-}
\ No newline at end of file
+// UpdatePasswordHash mengganti password_hash dan menaikkan token_version sehingga semua
+// access token lama tidak berlaku. Mengembalikan token_version yang baru.
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, userID, newHashedPassword string) (int, error) {
+    var version int
+    sql := `UPDATE users SET password_hash = $1, token_version = token_version + 1, updated_at = NOW()
+            WHERE id = $2 RETURNING token_version`
+    err := r.db.QueryRow(ctx, sql, newHashedPassword, userID).Scan(&version)
+    return version, err
+}
+
+// MarkEmailVerified mencatat bahwa user sudah membuktikan kepemilikan alamat emailnya.
+// Waktu verifikasi pertama dipertahankan.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID string) error {
+	sql := "UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW() WHERE id = $1"
+	result, err := r.db.Exec(ctx, sql, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetTokenState mengambil token_version user saat ini dan apakah akunnya dikunci.
+func (r *UserRepository) GetTokenState(ctx context.Context, userID string) (version int, locked bool, err error) {
+	sql := "SELECT token_version, locked_at IS NOT NULL FROM users WHERE id = $1"
+	err = r.db.QueryRow(ctx, sql, userID).Scan(&version, &locked)
+	return version, locked, err
+}
+
+// SetUserLocked mengunci atau membuka akun berdasarkan email dan mengembalikan ID user.
+// Mengunci akun juga menaikkan token_version.
+func (r *UserRepository) SetUserLocked(ctx context.Context, email string, locked bool) (string, error) {
+	var id string
+	sql := `UPDATE users SET
+	            locked_at = CASE WHEN $2 THEN COALESCE(locked_at, NOW()) END,
+	            token_version = token_version + CASE WHEN $2 THEN 1 ELSE 0 END,
+	            updated_at = NOW()
+	        WHERE email = $1 RETURNING id`
+	err := r.db.QueryRow(ctx, sql, email, locked).Scan(&id)
+	return id, err
+}
+
+// DeleteUser menghapus user beserta seluruh datanya (goal, tugas, token, dst. ikut terhapus).
+func (r *UserRepository) DeleteUser(ctx context.Context, userID string) error {
+	result, err := r.db.Exec(ctx, "DELETE FROM users WHERE id = $1", userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetUserLocale mengambil preferensi bahasa user untuk konten AI.
+func (r *UserRepository) GetUserLocale(ctx context.Context, userID string) (string, error) {
+	var locale string
+	sql := "SELECT locale FROM users WHERE id = $1"
+	err := r.db.QueryRow(ctx, sql, userID).Scan(&locale)
+	return locale, err
+}
+
+// UpdateLocale menyimpan preferensi bahasa user.
+func (r *UserRepository) UpdateLocale(ctx context.Context, userID, locale string) error {
+	sql := "UPDATE users SET locale = $1, updated_at = NOW() WHERE id = $2"
+	result, err := r.db.Exec(ctx, sql, locale, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
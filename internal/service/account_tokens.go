@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/mail"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidEmail        = errors.New("invalid email address")
+	ErrEmailNotVerified    = errors.New("email not verified")
+	ErrInvalidAccountToken = errors.New("invalid or expired token")
+	ErrPasswordRequired    = errors.New("password cannot be empty")
+)
+
+// accountTokenCooldown membatasi seberapa sering email verifikasi/reset bisa diminta ulang
+// untuk satu akun, agar endpoint publik ini tidak dipakai membanjiri inbox orang lain.
+const accountTokenCooldown = time.Minute
+
+// accountEmail adalah isi email untuk satu tujuan token. body memakai %[1]s untuk link dan
+// %[2]s untuk masa berlakunya.
+type accountEmail struct {
+	subject string
+	body    string
+	path    string // Halaman frontend yang menerima ?token=
+}
+
+var accountEmails = map[string]map[string]accountEmail{
+	LocaleIndonesian: {
+		repository.AccountTokenVerifyEmail: {
+			subject: "Verifikasi email akun Momentum kamu",
+			body:    "Halo!\n\nBuka link berikut untuk memverifikasi email akun Momentum kamu:\n\n%[1]s\n\nLink ini berlaku selama %[2]s. Abaikan email ini jika kamu tidak mendaftar di Momentum.\n",
+			path:    "/verify-email",
+		},
+		repository.AccountTokenResetPassword: {
+			subject: "Reset password Momentum",
+			body:    "Halo!\n\nSeseorang meminta reset password untuk akun Momentum kamu. Buka link berikut untuk membuat password baru:\n\n%[1]s\n\nLink ini berlaku selama %[2]s dan hanya bisa dipakai sekali. Abaikan email ini jika kamu tidak memintanya; password kamu tidak berubah.\n",
+			path:    "/reset-password",
+		},
+	},
+	LocaleEnglish: {
+		repository.AccountTokenVerifyEmail: {
+			subject: "Verify your Momentum email",
+			body:    "Hi!\n\nOpen the link below to verify the email address of your Momentum account:\n\n%[1]s\n\nThis link is valid for %[2]s. If you did not sign up for Momentum, you can ignore this email.\n",
+			path:    "/verify-email",
+		},
+		repository.AccountTokenResetPassword: {
+			subject: "Reset your Momentum password",
+			body:    "Hi!\n\nSomeone requested a password reset for your Momentum account. Open the link below to choose a new password:\n\n%[1]s\n\nThis link is valid for %[2]s and can only be used once. If you did not request it, ignore this email; your password has not changed.\n",
+			path:    "/reset-password",
+		},
+	},
+}
+
+// RequestEmailVerification mengirim ulang email verifikasi di background. Email yang tidak
+// terdaftar atau sudah terverifikasi diabaikan. Pemanggil tidak menunggu dan tidak menerima
+// error apa pun, sehingga respons dan waktunya sama untuk semua email.
+func (s *AuthService) RequestEmailVerification(ctx context.Context, email string) {
+	s.inBackground(ctx, "sending verification email", func(ctx context.Context) error {
+		user, err := s.userRepo.GetUserByEmail(ctx, strings.TrimSpace(email))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		return s.sendAccountToken(ctx, user, repository.AccountTokenVerifyEmail, s.verifyTTL)
+	})
+}
+
+// ConfirmEmailVerification memakai token dari email verifikasi.
+func (s *AuthService) ConfirmEmailVerification(ctx context.Context, token string) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		userID, err := consumeAccountToken(ctx, s.accountTokens.WithTx(tx), repository.AccountTokenVerifyEmail, token)
+		if err != nil {
+			return err
+		}
+		return s.userRepo.WithTx(tx).MarkEmailVerified(ctx, userID)
+	})
+}
+
+// RequestPasswordReset mengirim email berisi link reset password di background. Seperti
+// verifikasi, email yang tidak terdaftar diabaikan dan pemanggil tidak menerima error.
+// Akun yang dikunci admin tidak dikirimi link.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) {
+	s.inBackground(ctx, "sending password reset email", func(ctx context.Context) error {
+		user, err := s.userRepo.GetUserByEmail(ctx, strings.TrimSpace(email))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		if user.LockedAt != nil {
+			return nil
+		}
+		return s.sendAccountToken(ctx, user, repository.AccountTokenResetPassword, s.resetTTL)
+	})
+}
+
+// ResetPassword mengganti password memakai token dari email reset. Seperti ChangePassword,
+// semua sesi lama dicabut. Karena user terbukti memegang inbox-nya, email juga dianggap
+// terverifikasi. Token hanya terpakai jika semua langkah berhasil (satu transaksi).
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if newPassword == "" {
+		return ErrPasswordRequired
+	}
+	if token == "" {
+		return ErrInvalidAccountToken
+	}
+	// Hash di luar transaksi agar bcrypt tidak menahan koneksi database
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	var userID string
+	err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		users := s.userRepo.WithTx(tx)
+		var err error
+		if userID, err = consumeAccountToken(ctx, s.accountTokens.WithTx(tx), repository.AccountTokenResetPassword, token); err != nil {
+			return err
+		}
+		if _, err := users.UpdatePasswordHash(ctx, userID, string(hashed)); err != nil {
+			return err
+		}
+		if err := s.refreshRepo.WithTx(tx).RevokeUserTokens(ctx, userID); err != nil {
+			return err
+		}
+		return users.MarkEmailVerified(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+	s.tokenStates.invalidate(userID)
+	return nil
+}
+
+// sendAccountToken membuat token sekali pakai lalu mengirim link-nya ke email user. Token
+// sebelumnya dengan tujuan yang sama otomatis tidak berlaku. Permintaan yang datang sebelum
+// accountTokenCooldown lewat diabaikan.
+func (s *AuthService) sendAccountToken(ctx context.Context, user *repository.User, purpose string, ttl time.Duration) error {
+	last, err := s.accountTokens.LatestAccountTokenAt(ctx, user.ID, purpose)
+	if err != nil {
+		return err
+	}
+	if last != nil && time.Since(*last) < accountTokenCooldown {
+		return nil
+	}
+
+	token, err := newRefreshToken()
+	if err != nil {
+		return err
+	}
+	if err := s.accountTokens.CreateAccountToken(ctx, user.ID, purpose, hashRefreshToken(token), time.Now().Add(ttl)); err != nil {
+		return err
+	}
+
+	locale := user.Locale
+	if _, ok := accountEmails[locale]; !ok {
+		locale = DefaultLocale
+	}
+	content := accountEmails[locale][purpose]
+	link := s.appBaseURL + content.path + "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: content.subject,
+		Body:    fmt.Sprintf(content.body, link, formatValidity(locale, ttl)),
+	})
+}
+
+func consumeAccountToken(ctx context.Context, tokens *repository.AccountTokenRepository, purpose, token string) (string, error) {
+	if token == "" {
+		return "", ErrInvalidAccountToken
+	}
+	userID, err := tokens.ConsumeAccountToken(ctx, purpose, hashRefreshToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrInvalidAccountToken
+	}
+	return userID, err
+}
+
+// sendVerificationAfterRegister mengirim email verifikasi di background setelah registrasi,
+// agar latensi SMTP tidak menahan respons. Gagal mengirim tidak menggagalkan registrasi;
+// user bisa meminta ulang lewat RequestEmailVerification.
+func (s *AuthService) sendVerificationAfterRegister(ctx context.Context, user *repository.User) {
+	s.inBackground(ctx, "sending verification email", func(ctx context.Context) error {
+		return s.sendAccountToken(ctx, user, repository.AccountTokenVerifyEmail, s.verifyTTL)
+	})
+}
+
+// accountEmailTimeout membatasi satu pengiriman email di background, termasuk query-nya.
+const accountEmailTimeout = 30 * time.Second
+
+// inBackground menjalankan fn di goroutine terpisah yang tidak ikut berhenti ketika request
+// selesai. Error hanya di-log.
+func (s *AuthService) inBackground(ctx context.Context, action string, fn func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), accountEmailTimeout)
+	go func() {
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			log.Printf("ERROR %s: %v", action, err)
+		}
+	}()
+}
+
+// formatValidity menuliskan masa berlaku link dalam jam (mulai 2 jam) atau menit.
+func formatValidity(locale string, d time.Duration) string {
+	hours, minutes := "jam", "menit"
+	if locale == LocaleEnglish {
+		hours, minutes = "hours", "minutes"
+	}
+	if d >= 2*time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d %s", int(d/time.Hour), hours)
+	}
+	return fmt.Sprintf("%d %s", int(d/time.Minute), minutes)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+type aiScopeKey struct{}
+
+// aiScope menyimpan siapa (user) dan untuk apa (goal) sebuah panggilan AI dibuat,
+// agar log generasi bisa diatribusikan tanpa mengubah signature AIService.
+type aiScope struct {
+	UserID string
+	GoalID string
+}
+
+func withAIScope(ctx context.Context, userID, goalID string) context.Context {
+	return context.WithValue(ctx, aiScopeKey{}, aiScope{UserID: userID, GoalID: goalID})
+}
+
+func aiScopeFrom(ctx context.Context) aiScope {
+	scope, _ := ctx.Value(aiScopeKey{}).(aiScope)
+	return scope
+}
+
+// generationRecord adalah data satu panggilan AI yang akan disimpan ke ai_generations.
+type generationRecord struct {
+	Kind        GenerationKind
+	Attempt     int
+	Locale      string
+	Prompt      string
+	Response    *LLMResponse
+	CleanedJSON string
+	Outcome     string
+	Err         error
+	Latency     time.Duration
+}
+
+// recordGeneration menyimpan log panggilan AI. Kegagalan menyimpan log tidak boleh
+// menggagalkan alur utama, jadi error hanya dicatat.
+func (s *AIService) recordGeneration(ctx context.Context, rec generationRecord) {
+	if s.generationRepo == nil {
+		return
+	}
+
+	scope := aiScopeFrom(ctx)
+	g := &repository.AIGeneration{
+		UserID:        nullableString(scope.UserID),
+		GoalID:        nullableString(scope.GoalID),
+		Kind:          string(rec.Kind),
+		Provider:      s.provider.Name(),
+		Attempt:       rec.Attempt,
+		PromptVersion: s.prompts.version,
+		Locale:        rec.Locale,
+		Prompt:        rec.Prompt,
+		Outcome:       rec.Outcome,
+		LatencyMs:     int(rec.Latency.Milliseconds()),
+	}
+	if rec.CleanedJSON != "" {
+		g.CleanedJSON = &rec.CleanedJSON
+	}
+	if rec.Err != nil {
+		msg := rec.Err.Error()
+		g.ErrorMessage = &msg
+	}
+	if rec.Response != nil {
+		g.RawResponse = &rec.Response.Text
+		g.PromptTokens = nullableInt(rec.Response.PromptTokens)
+		g.CompletionTokens = nullableInt(rec.Response.CompletionTokens)
+		g.TotalTokens = nullableInt(rec.Response.TotalTokens)
+	}
+
+	// Tetap simpan log walaupun request aslinya sudah dibatalkan/timeout
+	if err := s.generationRepo.CreateGeneration(context.WithoutCancel(ctx), g); err != nil {
+		log.Printf("ERROR saving AI generation log: %v", err)
+	}
+}
+
+// ListGenerations mengambil riwayat panggilan AI milik user.
+func (s *AIService) ListGenerations(ctx context.Context, userID string, limit, offset int) ([]repository.AIGeneration, error) {
+	return s.generationRepo.ListGenerationsByUserID(ctx, userID, limit, offset)
+}
+
+func nullableString(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func nullableInt(v int) *int {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// AIOutputError dikembalikan ketika respons AI tetap tidak sesuai skema
+// setelah semua percobaan perbaikan habis.
+type AIOutputError struct {
+	Kind     GenerationKind
+	Attempts int
+	Problems []string
+}
+
+func (e *AIOutputError) Error() string {
+	return fmt.Sprintf("output AI (%s) tidak valid setelah %d percobaan: %s", e.Kind, e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+// outputSchema mendeskripsikan bentuk JSON array yang kita harapkan dari AI.
+type outputSchema struct {
+	MinItems         int
+	MaxItems         int
+	MaxTitleLen      int
+	RequireStepOrder bool
+	MaxEstimatedDays int // Batas atas estimated_days (opsional); 0 berarti field diabaikan
+	MaxSubSteps      int // Batas sub_steps per item (opsional); 0 berarti field diabaikan
+}
+
+var (
+	roadmapSchema   = outputSchema{MinItems: 3, MaxItems: 5, MaxTitleLen: 150, RequireStepOrder: true, MaxEstimatedDays: 365, MaxSubSteps: 5}
+	dailyTaskSchema = outputSchema{MinItems: 1, MaxItems: 6, MaxTitleLen: 150}
+
+	stepSplitSchema    = outputSchema{MinItems: 2, MaxItems: 5, MaxTitleLen: 150}
+	stepRephraseSchema = outputSchema{MinItems: 1, MaxItems: 1, MaxTitleLen: 150}
+	roadmapTailSchema  = outputSchema{MinItems: 1, MaxItems: 5, MaxTitleLen: 150, RequireStepOrder: true, MaxEstimatedDays: 365, MaxSubSteps: 5}
+)
+
+// aiItem adalah satu elemen array yang dikembalikan AI (langkah roadmap atau tugas).
+type aiItem struct {
+	StepOrder     *int     `json:"step_order"`
+	Title         string   `json:"title"`
+	EstimatedDays *int     `json:"estimated_days"`
+	Milestone     bool     `json:"milestone"`
+	SubSteps      []aiItem `json:"sub_steps"` // Hanya satu tingkat; sub_steps milik sub-langkah diabaikan
+}
+
+// validate mem-parsing cleanedJSON dan mengembalikan daftar masalah yang ditemukan.
+// Jika daftar masalah kosong, item sudah diurutkan berdasarkan step_order (bila ada).
+func (sc outputSchema) validate(cleanedJSON string) ([]aiItem, []string) {
+	if strings.TrimSpace(cleanedJSON) == "" {
+		return nil, []string{"respons tidak mengandung JSON array"}
+	}
+
+	var items []aiItem
+	if err := json.Unmarshal([]byte(cleanedJSON), &items); err != nil {
+		return nil, []string{fmt.Sprintf("JSON tidak valid: %v", err)}
+	}
+
+	var problems []string
+	if len(items) < sc.MinItems || len(items) > sc.MaxItems {
+		problems = append(problems, fmt.Sprintf("jumlah item harus %d sampai %d, didapat %d", sc.MinItems, sc.MaxItems, len(items)))
+	}
+
+	for i, item := range items {
+		title := strings.TrimSpace(item.Title)
+		if title == "" {
+			problems = append(problems, fmt.Sprintf("item ke-%d: field \"title\" wajib diisi", i+1))
+		} else if utf8.RuneCountInString(title) > sc.MaxTitleLen {
+			problems = append(problems, fmt.Sprintf("item ke-%d: \"title\" maksimal %d karakter", i+1, sc.MaxTitleLen))
+		}
+		items[i].Title = title
+
+		if sc.MaxEstimatedDays == 0 {
+			items[i].EstimatedDays = nil
+		} else if days := item.EstimatedDays; days != nil && (*days < 1 || *days > sc.MaxEstimatedDays) {
+			problems = append(problems, fmt.Sprintf("item ke-%d: \"estimated_days\" harus di antara 1 dan %d", i+1, sc.MaxEstimatedDays))
+		}
+
+		if sc.MaxSubSteps == 0 {
+			items[i].SubSteps = nil
+			continue
+		}
+		if len(item.SubSteps) > sc.MaxSubSteps {
+			problems = append(problems, fmt.Sprintf("item ke-%d: maksimal %d \"sub_steps\", didapat %d", i+1, sc.MaxSubSteps, len(item.SubSteps)))
+		}
+		for j := range item.SubSteps {
+			sub := &items[i].SubSteps[j]
+			sub.Title = strings.TrimSpace(sub.Title)
+			sub.SubSteps = nil
+			if sub.Title == "" {
+				problems = append(problems, fmt.Sprintf("item ke-%d, sub-langkah ke-%d: field \"title\" wajib diisi", i+1, j+1))
+			} else if utf8.RuneCountInString(sub.Title) > sc.MaxTitleLen {
+				problems = append(problems, fmt.Sprintf("item ke-%d, sub-langkah ke-%d: \"title\" maksimal %d karakter", i+1, j+1, sc.MaxTitleLen))
+			}
+		}
+	}
+
+	if sc.RequireStepOrder {
+		seen := make(map[int]bool, len(items))
+		for i, item := range items {
+			if item.StepOrder == nil {
+				problems = append(problems, fmt.Sprintf("item ke-%d: field \"step_order\" wajib diisi", i+1))
+				continue
+			}
+			order := *item.StepOrder
+			if order < 1 || order > len(items) || seen[order] {
+				problems = append(problems, fmt.Sprintf("item ke-%d: \"step_order\" harus unik dan berurutan mulai dari 1", i+1))
+			}
+			seen[order] = true
+		}
+		if len(problems) == 0 {
+			sort.Slice(items, func(a, b int) bool { return *items[a].StepOrder < *items[b].StepOrder })
+		}
+	}
+
+	return items, problems
+}
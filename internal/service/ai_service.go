@@ -4,41 +4,218 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
-	"github.com/google/generative-ai-go/genai"
-	"google.golang.org/api/option"
 )
 
 type AIService struct {
-	genaiClient *genai.GenerativeModel
+	provider        LLMProvider
+	generationRepo  *repository.AIGenerationRepository
+	userRepo        *repository.UserRepository
+	prompts         *promptSet
+	repairAttempts  int                              // Berapa kali AI diminta memperbaiki output yang tidak valid
+	timeouts        map[GenerationKind]time.Duration // Batas waktu per jenis operasi (termasuk percobaan perbaikan)
+	breaker         *CircuitBreaker                  // Menolak panggilan sementara setelah provider gagal berturut-turut
+	fallbackEnabled bool                             // Pakai template cadangan jika AI gagal
 }
 
-func NewAIService() *AIService {
-	ctx := context.Background()
-	apiKey := config.Get("GEMINI_API_KEY")
-	if apiKey == "" {
-		log.Fatal("GEMINI_API_KEY environment variable is not set")
+func NewAIService(provider LLMProvider, generationRepo *repository.AIGenerationRepository, userRepo *repository.UserRepository) *AIService {
+	promptVersion := config.Get("AI_PROMPT_VERSION")
+	if promptVersion == "" {
+		promptVersion = "v1"
 	}
+	prompts, err := loadPromptSet(promptVersion)
+	if err != nil {
+		log.Fatalf("Failed to load AI prompt templates: %v", err)
+	}
+
+	repairAttempts := 2
+	if v, err := strconv.Atoi(config.Get("AI_MAX_REPAIR_ATTEMPTS")); err == nil && v >= 0 {
+		repairAttempts = v
+	}
+	// AI_TIMEOUT_SECONDS menjadi default untuk setiap operasi yang tidak diatur tersendiri
+	timeout := secondsFromConfig("AI_TIMEOUT_SECONDS", 30*time.Second)
+	roadmapTimeout := secondsFromConfig("AI_ROADMAP_TIMEOUT_SECONDS", timeout)
+	timeouts := map[GenerationKind]time.Duration{
+		KindRoadmap:        roadmapTimeout,
+		KindStepSplit:      roadmapTimeout,
+		KindStepRephrase:   roadmapTimeout,
+		KindRoadmapTail:    roadmapTimeout,
+		KindDailyTasks:     secondsFromConfig("AI_DAILY_TASKS_TIMEOUT_SECONDS", timeout),
+		KindReviewFeedback: secondsFromConfig("AI_REVIEW_TIMEOUT_SECONDS", timeout),
+	}
+
+	threshold := intFromConfig("AI_BREAKER_FAILURE_THRESHOLD", 5)
+	if threshold < 1 {
+		threshold = 5
+	}
+	cooldown := secondsFromConfig("AI_BREAKER_COOLDOWN_SECONDS", 30*time.Second)
+
+	return &AIService{
+		provider:        provider,
+		generationRepo:  generationRepo,
+		userRepo:        userRepo,
+		prompts:         prompts,
+		repairAttempts:  repairAttempts,
+		timeouts:        timeouts,
+		breaker:         NewCircuitBreaker(threshold, cooldown),
+		fallbackEnabled: config.Get("AI_FALLBACK_ENABLED") != "false",
+	}
+}
+
+// secondsFromConfig membaca durasi dalam detik; nilai kosong atau tidak positif memakai def.
+func secondsFromConfig(key string, def time.Duration) time.Duration {
+	if v, err := strconv.Atoi(config.Get(key)); err == nil && v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	return def
+}
+
+// AIHealth adalah status provider AI untuk endpoint GET /api/health/ai.
+type AIHealth struct {
+	Provider        string               `json:"provider"`
+	Circuit         CircuitBreakerStatus `json:"circuit"`
+	Timeouts        map[string]int       `json:"timeouts_seconds"`
+	FallbackEnabled bool                 `json:"fallback_enabled"`
+}
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+// Health mengembalikan state circuit breaker dan konfigurasi timeout saat ini.
+func (s *AIService) Health() AIHealth {
+	timeouts := make(map[string]int, len(s.timeouts))
+	for kind, d := range s.timeouts {
+		timeouts[string(kind)] = int(d.Seconds())
+	}
+	return AIHealth{
+		Provider:        s.provider.Name(),
+		Circuit:         s.breaker.Status(),
+		Timeouts:        timeouts,
+		FallbackEnabled: s.fallbackEnabled,
+	}
+}
+
+// withTimeout memberi batas waktu sesuai jenis operasi AI.
+func (s *AIService) withTimeout(ctx context.Context, kind GenerationKind) (context.Context, context.CancelFunc) {
+	timeout, ok := s.timeouts[kind]
+	if !ok {
+		timeout = 30 * time.Second
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// callProvider meneruskan satu panggilan ke provider melalui circuit breaker. Jika onChunk
+// tidak nil dan provider mendukung streaming, teks dikirim ke onChunk selagi dihasilkan.
+// Pembatalan dari klien dan error dari onChunk tidak dihitung sebagai kegagalan provider.
+func (s *AIService) callProvider(ctx context.Context, req LLMRequest, onChunk func(chunk string) error) (*LLMResponse, error) {
+	if err := s.breaker.Allow(); err != nil {
+		return nil, &AIUnavailableError{Provider: s.provider.Name(), RetryAfter: s.breaker.RetryAfter()}
+	}
+	// Kuota baru dipakai setelah breaker mengizinkan, jadi hanya panggilan provider sungguhan yang dihitung
+	if err := chargeQuota(ctx); err != nil {
+		s.breaker.RecordIgnored()
+		return nil, err
+	}
+
+	var resp *LLMResponse
+	var err error
+	var chunkErr error
+	if streamer, ok := s.provider.(StreamingLLMProvider); ok && onChunk != nil {
+		resp, err = streamer.GenerateStream(ctx, req, func(chunk string) error {
+			chunkErr = onChunk(chunk)
+			return chunkErr
+		})
+	} else {
+		resp, err = s.provider.Generate(ctx, req)
+	}
+
+	switch {
+	case err == nil:
+		s.breaker.RecordSuccess()
+	case chunkErr != nil || errors.Is(err, context.Canceled):
+		s.breaker.RecordIgnored()
+	default:
+		s.breaker.RecordFailure(err)
+		if status := s.breaker.Status(); status.State == CircuitOpen {
+			log.Printf("Circuit breaker AI (%s) terbuka setelah %d kegagalan berturut-turut: %s", s.provider.Name(), status.ConsecutiveFailures, status.LastError)
+		}
+	}
 	if err != nil {
-		log.Fatalf("Failed to create genai client: %v", err)
+		return nil, err
 	}
 
-	model := client.GenerativeModel("gemini-1.5-flash")
-	model.SetTemperature(0.7)
-	return &AIService{genaiClient: model}
+	if onChunk != nil && chunkErr == nil {
+		if _, ok := s.provider.(StreamingLLMProvider); !ok && strings.TrimSpace(resp.Text) != "" {
+			if err := onChunk(resp.Text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return resp, nil
+}
+
+// RoadmapWithFallback membuat roadmap dengan AI, atau dengan template jika AI gagal/timeout.
+// Nilai kedua adalah asal konten (repository.SourceAI atau repository.SourceTemplate).
+func (s *AIService) RoadmapWithFallback(ctx context.Context, goalDescription string, targetDate *time.Time) ([]repository.RoadmapStep, string, error) {
+	steps, err := s.GenerateRoadmapWithAI(ctx, goalDescription, targetDate)
+	if err == nil {
+		return steps, repository.SourceAI, nil
+	}
+	if !s.fallbackEnabled || ctx.Err() != nil || isQuotaExceeded(err) {
+		return nil, "", err
+	}
+	log.Printf("AI gagal membuat roadmap, memakai template cadangan: %v", err)
+	return fallbackRoadmap(s.localeFor(ctx), goalDescription), repository.SourceTemplate, nil
+}
+
+// DailyTasksWithFallback membuat hingga taskCount tugas harian dengan AI, atau dengan template jika AI gagal/timeout.
+func (s *AIService) DailyTasksWithFallback(ctx context.Context, goalDesc string, currentStepTitle string, taskCount int, dailyCtx *DailyTaskContext) ([]repository.Task, string, error) {
+	tasks, err := s.GenerateDailyTasksWithAI(ctx, goalDesc, currentStepTitle, taskCount, dailyCtx)
+	if err == nil {
+		return tasks, repository.SourceAI, nil
+	}
+	if !s.fallbackEnabled || ctx.Err() != nil || isQuotaExceeded(err) {
+		return nil, "", err
+	}
+	log.Printf("AI gagal membuat tugas harian, memakai template cadangan: %v", err)
+	tasks = fallbackDailyTasks(s.localeFor(ctx), currentStepTitle)
+	if len(tasks) > taskCount {
+		tasks = tasks[:taskCount]
+	}
+	return tasks, repository.SourceTemplate, nil
+}
+
+// DefaultReviewFeedback adalah feedback statis (sesuai locale user) saat AI tidak bisa dipakai.
+func (s *AIService) DefaultReviewFeedback(ctx context.Context) string {
+	text, err := s.prompts.render(s.localeFor(ctx), promptFeedbackFallback, nil)
+	if err != nil {
+		log.Printf("ERROR rendering fallback feedback: %v", err)
+		return "Tetap semangat untuk esok hari!"
+	}
+	return text
+}
+
+// localeFor menentukan bahasa konten AI dari preferensi user pada scope panggilan.
+func (s *AIService) localeFor(ctx context.Context) string {
+	scope := aiScopeFrom(ctx)
+	if scope.UserID == "" || s.userRepo == nil {
+		return DefaultLocale
+	}
+	locale, err := s.userRepo.GetUserLocale(ctx, scope.UserID)
+	if err != nil || !IsSupportedLocale(locale) {
+		return DefaultLocale
+	}
+	return locale
 }
 
 // --- FUNGSI BANTUAN BARU UNTUK MEMBERSIHKAN JSON ---
-func cleanAIResponseToJSON(rawResponse genai.Text) string {
-    rawStr := string(rawResponse)
+func cleanAIResponseToJSON(rawStr string) string {
     log.Printf("Respons mentah dari AI: %s", rawStr)
 
     // Langsung cari blok yang diawali dengan [ dan diakhiri dengan ]
@@ -54,91 +231,203 @@ func cleanAIResponseToJSON(rawResponse genai.Text) string {
     return jsonString
 }
 
-// GenerateRoadmapWithAI membuat roadmap berdasarkan deskripsi tujuan.
-func (s *AIService) GenerateRoadmapWithAI(ctx context.Context, goalDescription string) ([]repository.RoadmapStep, error) {
-	log.Println("Memanggil AI Gemini untuk membuat roadmap...")
-	prompt := fmt.Sprintf(
-		`Sebagai seorang productivity coach, buatkan roadmap untuk tujuan ini: "%s". 
-		Berikan 3 sampai 5 langkah utama yang realistis. 
-		JAWAB HANYA DENGAN FORMAT JSON ARRAY seperti ini, tanpa teks pembuka atau penutup sama sekali: 
-		[{"step_order": 1, "title": "Judul Langkah 1"}, {"step_order": 2, "title": "Judul Langkah 2"}]`,
-		goalDescription,
-	)
+// generateStructured memanggil AI dan memvalidasi JSON array hasilnya terhadap schema.
+// Jika tidak valid, AI diminta memperbaiki jawabannya hingga s.repairAttempts kali
+// sebelum menyerah dengan *AIOutputError.
+func (s *AIService) generateStructured(ctx context.Context, kind GenerationKind, locale, prompt string, schema outputSchema) ([]aiItem, error) {
+	ctx, cancel := s.withTimeout(ctx, kind)
+	defer cancel()
+
+	currentPrompt := prompt
+	var problems []string
+	attempts := 0
+
+	for attempts <= s.repairAttempts {
+		attempts++
+		rec := generationRecord{Kind: kind, Attempt: attempts, Prompt: currentPrompt, Locale: locale}
+		start := time.Now()
+		resp, err := s.callProvider(ctx, LLMRequest{Kind: kind, Prompt: currentPrompt}, nil)
+		rec.Latency = time.Since(start)
+		if errors.Is(err, ErrAIUnavailable) || isQuotaExceeded(err) {
+			return nil, err // Provider tidak dipanggil, tidak ada yang perlu dicatat
+		}
+		if err != nil {
+			rec.Outcome, rec.Err = repository.GenerationOutcomeProviderError, err
+			s.recordGeneration(ctx, rec)
+			return nil, fmt.Errorf("gagal menghasilkan %s dari AI: %w", kind, err)
+		}
+		rec.Response = resp
+
+		// Gunakan helper untuk membersihkan respons
+		cleanedJSON := cleanAIResponseToJSON(resp.Text)
+		log.Printf("Respons AI (%s) setelah dibersihkan: %s", kind, cleanedJSON)
+		rec.CleanedJSON = cleanedJSON
+
+		var items []aiItem
+		items, problems = schema.validate(cleanedJSON)
+		if len(problems) == 0 {
+			rec.Outcome = repository.GenerationOutcomeOK
+			s.recordGeneration(ctx, rec)
+			return items, nil
+		}
+
+		rec.Outcome = repository.GenerationOutcomeInvalidOutput
+		rec.Err = &AIOutputError{Kind: kind, Attempts: attempts, Problems: problems}
+		s.recordGeneration(ctx, rec)
+		log.Printf("Output AI (%s) tidak valid pada percobaan %d: %s", kind, attempts, strings.Join(problems, "; "))
+		currentPrompt, err = s.prompts.render(locale, promptRepair, map[string]any{
+			"OriginalPrompt":   prompt,
+			"PreviousResponse": resp.Text,
+			"Problems":         strings.Join(problems, "; "),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	resp, err := s.genaiClient.GenerateContent(ctx, genai.Text(prompt))
-	if err != nil {
-		return nil, fmt.Errorf("gagal menghasilkan konten dari AI: %w", err)
+	return nil, &AIOutputError{Kind: kind, Attempts: attempts, Problems: problems}
+}
+
+// GenerateRoadmapWithAI membuat roadmap berdasarkan deskripsi tujuan. Jika targetDate
+// diisi, AI diminta menyesuaikan estimasi durasi langkah dengan sisa waktu yang ada.
+func (s *AIService) GenerateRoadmapWithAI(ctx context.Context, goalDescription string, targetDate *time.Time) ([]repository.RoadmapStep, error) {
+	log.Printf("Memanggil AI (%s) untuk membuat roadmap...", s.provider.Name())
+	locale := s.localeFor(ctx)
+	data := map[string]any{
+		"GoalDescription": goalDescription,
+		"Today":           truncateToDate(time.Now()).Format("2006-01-02"),
+		"TargetDate":      "",
+		"DaysAvailable":   0,
 	}
-	
-	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("respons AI tidak valid atau kosong")
+	if targetDate != nil {
+		data["TargetDate"] = targetDate.Format("2006-01-02")
+		data["DaysAvailable"] = daysBetween(time.Now(), *targetDate)
 	}
-	
-	aiResponse := resp.Candidates[0].Content.Parts[0].(genai.Text)
-	
-	// Gunakan helper untuk membersihkan respons
-	cleanedJSON := cleanAIResponseToJSON(aiResponse)
-	log.Printf("Respons Roadmap AI setelah dibersihkan: %s", cleanedJSON)
-	
-	var steps []repository.RoadmapStep
-	if err := json.Unmarshal([]byte(cleanedJSON), &steps); err != nil {
-		return nil, fmt.Errorf("gagal mem-parsing JSON dari AI: %w. Respons AI: %s", err, cleanedJSON)
+	prompt, err := s.prompts.render(locale, promptRoadmap, data)
+	if err != nil {
+		return nil, err
 	}
 
-	return steps, nil
-}
+	items, err := s.generateStructured(ctx, KindRoadmap, locale, prompt, roadmapSchema)
+	if err != nil {
+		return nil, err
+	}
 
-// GenerateDailyTasksWithAI membuat daftar tugas harian berdasarkan konteks.
-func (s *AIService) GenerateDailyTasksWithAI(ctx context.Context, goalDesc string, currentStepTitle string, yesterdayTasks []repository.Task) ([]repository.Task, error) {
-	log.Println("Memanggil AI Gemini untuk membuat jadwal harian...")
+	return stepsFromItems(items), nil
+}
 
-	var yesterdaySummary string
-	if len(yesterdayTasks) > 0 {
-		// ... (logika untuk membuat yesterdaySummary tetap sama) ...
-	} else {
-		yesterdaySummary = "Ini adalah hari pertama, belum ada riwayat tugas."
+// GenerateStepSplit meminta AI memecah satu langkah menjadi beberapa langkah yang lebih kecil.
+// parentTitle diisi jika langkah tersebut adalah sub-langkah.
+func (s *AIService) GenerateStepSplit(ctx context.Context, goalDescription, stepTitle, parentTitle, instructions string) ([]repository.RoadmapStep, error) {
+	log.Printf("Memanggil AI (%s) untuk memecah langkah roadmap...", s.provider.Name())
+	items, err := s.generateStepItems(ctx, KindStepSplit, promptStepSplit, stepSplitSchema, goalDescription, stepTitle, parentTitle, instructions)
+	if err != nil {
+		return nil, err
 	}
+	return stepsFromItems(items), nil
+}
 
-	prompt := fmt.Sprintf(
-        `Sebagai seorang productivity coach, buatkan 3-4 tugas HARI INI.
-        Tujuan besar pengguna: "%s".
-        FOKUS UTAMA HARI INI adalah pada langkah roadmap: "%s".
-        Konteks dari kemarin: %s.
+// GenerateStepRephrase meminta AI merumuskan ulang judul satu langkah.
+func (s *AIService) GenerateStepRephrase(ctx context.Context, goalDescription, stepTitle, parentTitle, instructions string) ([]repository.RoadmapStep, error) {
+	log.Printf("Memanggil AI (%s) untuk merumuskan ulang langkah roadmap...", s.provider.Name())
+	items, err := s.generateStepItems(ctx, KindStepRephrase, promptStepRephrase, stepRephraseSchema, goalDescription, stepTitle, parentTitle, instructions)
+	if err != nil {
+		return nil, err
+	}
+	return stepsFromItems(items), nil
+}
 
-        Berdasarkan FOKUS UTAMA hari ini, berikan tugas-tugas yang sangat spesifik dan bisa dikerjakan.
-        JAWAB HANYA DENGAN FORMAT JSON ARRAY seperti ini, tanpa teks tambahan:
-        [{"title": "Judul Tugas Spesifik 1"}, {"title": "Judul Tugas Spesifik 2"}]`,
-        goalDesc,
-        currentStepTitle, // <-- Gunakan konteks baru
-        yesterdaySummary,
-    )
+func (s *AIService) generateStepItems(ctx context.Context, kind GenerationKind, promptName string, schema outputSchema, goalDescription, stepTitle, parentTitle, instructions string) ([]aiItem, error) {
+	locale := s.localeFor(ctx)
+	prompt, err := s.prompts.render(locale, promptName, map[string]any{
+		"GoalDescription": goalDescription,
+		"StepTitle":       stepTitle,
+		"ParentTitle":     parentTitle,
+		"Instructions":    instructions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.generateStructured(ctx, kind, locale, prompt, schema)
+}
 
-	resp, err := s.genaiClient.GenerateContent(ctx, genai.Text(prompt))
+// GenerateRoadmapTail membuat pengganti sisa roadmap yang belum dikerjakan. keptTitles adalah
+// langkah yang dipertahankan (sebagai konteks), replacedTitles adalah langkah yang akan diganti.
+func (s *AIService) GenerateRoadmapTail(ctx context.Context, goalDescription string, keptTitles, replacedTitles []string, instructions string, targetDate *time.Time) ([]repository.RoadmapStep, error) {
+	log.Printf("Memanggil AI (%s) untuk membuat ulang sisa roadmap...", s.provider.Name())
+	locale := s.localeFor(ctx)
+	data := map[string]any{
+		"GoalDescription": goalDescription,
+		"CompletedSteps":  keptTitles,
+		"ReplacedSteps":   replacedTitles,
+		"Instructions":    instructions,
+		"Today":           truncateToDate(time.Now()).Format("2006-01-02"),
+		"TargetDate":      "",
+		"DaysAvailable":   0,
+	}
+	if targetDate != nil {
+		data["TargetDate"] = targetDate.Format("2006-01-02")
+		data["DaysAvailable"] = daysBetween(time.Now(), *targetDate)
+	}
+	prompt, err := s.prompts.render(locale, promptRoadmapTail, data)
 	if err != nil {
-		return nil, fmt.Errorf("gagal menghasilkan tugas harian dari AI: %w", err)
+		return nil, err
 	}
 
-	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("respons AI tidak valid atau kosong untuk tugas harian")
+	items, err := s.generateStructured(ctx, KindRoadmapTail, locale, prompt, roadmapTailSchema)
+	if err != nil {
+		return nil, err
 	}
+	return stepsFromItems(items), nil
+}
 
-	aiResponse := resp.Candidates[0].Content.Parts[0].(genai.Text)
+// stepsFromItems mengubah item AI yang sudah divalidasi menjadi langkah roadmap. Item tanpa
+// step_order diurutkan sesuai posisinya di array, begitu juga sub-langkahnya.
+func stepsFromItems(items []aiItem) []repository.RoadmapStep {
+	steps := make([]repository.RoadmapStep, 0, len(items))
+	for i, item := range items {
+		order := i + 1
+		if item.StepOrder != nil {
+			order = *item.StepOrder
+		}
+		step := repository.RoadmapStep{Order: order, Title: item.Title, EstimatedDays: item.EstimatedDays, IsMilestone: item.Milestone}
+		for j, sub := range item.SubSteps {
+			step.Children = append(step.Children, repository.RoadmapStep{Order: j + 1, Title: sub.Title, IsMilestone: sub.Milestone})
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
 
-	// Gunakan helper untuk membersihkan respons
-	cleanedJSON := cleanAIResponseToJSON(aiResponse)
-	log.Printf("Respons Tugas Harian AI setelah dibersihkan: %s", cleanedJSON)
+// GenerateDailyTasksWithAI membuat daftar tugas harian berdasarkan konteks
+// (riwayat tugas, progres langkah roadmap, dan feedback review terakhir).
+func (s *AIService) GenerateDailyTasksWithAI(ctx context.Context, goalDesc string, currentStepTitle string, taskCount int, dailyCtx *DailyTaskContext) ([]repository.Task, error) {
+	log.Printf("Memanggil AI (%s) untuk membuat jadwal harian...", s.provider.Name())
+
+	locale := s.localeFor(ctx)
+	prompt, err := s.prompts.render(locale, promptDailyTasks, map[string]any{
+		"GoalDescription": goalDesc,
+		"StepTitle":       currentStepTitle,
+		"TaskCount":       taskCount,
+		"Context":         dailyCtx,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	type AITask struct {
-		Title string `json:"title"`
+	items, err := s.generateStructured(ctx, KindDailyTasks, locale, prompt, dailyTaskSchema)
+	if err != nil {
+		return nil, err
 	}
-	var aiTasks []AITask
-	if err := json.Unmarshal([]byte(cleanedJSON), &aiTasks); err != nil {
-		return nil, fmt.Errorf("gagal mem-parsing JSON dari AI untuk tugas harian: %w. Respons AI: %s", err, cleanedJSON)
+
+	// AI kadang memberi lebih banyak tugas dari yang diminta; porsi goal lain tidak boleh terpakai
+	if len(items) > taskCount {
+		items = items[:taskCount]
 	}
 
 	var newTasks []repository.Task
-	for _, t := range aiTasks {
-		newTasks = append(newTasks, repository.Task{Title: t.Title})
+	for _, item := range items {
+		newTasks = append(newTasks, repository.Task{Title: item.Title})
 	}
 
 	return newTasks, nil
@@ -147,7 +436,17 @@ func (s *AIService) GenerateDailyTasksWithAI(ctx context.Context, goalDesc strin
 
 // GenerateReviewFeedback membuat feedback motivasional (TIDAK PERLU PEMBERSIH JSON).
 func (s *AIService) GenerateReviewFeedback(ctx context.Context, goalDesc string, summary []repository.TaskSummary) (string, error) {
-	log.Println("Memanggil AI Gemini untuk membuat feedback review yang kontekstual...")
+	return s.generateReviewFeedback(ctx, goalDesc, summary, nil)
+}
+
+// StreamReviewFeedback sama seperti GenerateReviewFeedback, tetapi mengirim teks ke onChunk
+// selagi dihasilkan. Provider yang tidak mendukung streaming mengirim seluruh teks sekaligus.
+func (s *AIService) StreamReviewFeedback(ctx context.Context, goalDesc string, summary []repository.TaskSummary, onChunk func(chunk string) error) (string, error) {
+	return s.generateReviewFeedback(ctx, goalDesc, summary, onChunk)
+}
+
+func (s *AIService) generateReviewFeedback(ctx context.Context, goalDesc string, summary []repository.TaskSummary, onChunk func(chunk string) error) (string, error) {
+	log.Printf("Memanggil AI (%s) untuk membuat feedback review yang kontekstual...", s.provider.Name())
 
     // --- LOGIKA BARU UNTUK MEMBUAT NARASI ---
 	completedCount := 0
@@ -164,32 +463,40 @@ func (s *AIService) GenerateReviewFeedback(ctx context.Context, goalDesc string,
         }
 	}
 
-    narrative := fmt.Sprintf(
-        "Pengguna menyelesaikan %d tugas, melewatkan %d tugas, dan masih memiliki %d tugas yang belum selesai.",
-        completedCount,
-        missedCount,
-        pendingCount,
-    )
+    // Narasi dirangkai oleh template sesuai bahasa user
+	locale := s.localeFor(ctx)
+	prompt, err := s.prompts.render(locale, promptReviewFeedback, map[string]any{
+		"GoalDescription": goalDesc,
+		"Completed":       completedCount,
+		"Missed":          missedCount,
+		"Pending":         pendingCount,
+	})
+	if err != nil {
+		return "", err
+	}
     // --- AKHIR LOGIKA NARASI ---
 
-	prompt := fmt.Sprintf(
-		`Anda adalah seorang productivity coach yang suportif. Tujuan besar pengguna adalah: "%s".
-		Berikut adalah ringkasan performa mereka hari ini: "%s".
-		Berikan feedback singkat (2-3 kalimat) yang positif dan membangun. Jika ada tugas yang selesai, puji progres mereka menuju tujuan besarnya. Jika tidak ada yang selesai, berikan semangat tanpa menghakimi untuk mencoba lagi besok.
-        JAWAB SEBAGAI COACH, BUKAN SEBAGAI ASISTEN. JANGAN GUNAKAN FORMAT JSON.`,
-		goalDesc,
-		narrative,
-	)
+	genCtx, cancel := s.withTimeout(ctx, KindReviewFeedback)
+	defer cancel()
 
-	resp, err := s.genaiClient.GenerateContent(ctx, genai.Text(prompt))
+	rec := generationRecord{Kind: KindReviewFeedback, Attempt: 1, Prompt: prompt, Locale: locale}
+	start := time.Now()
+	resp, err := s.callProvider(genCtx, LLMRequest{Kind: KindReviewFeedback, Prompt: prompt}, onChunk)
+	rec.Latency = time.Since(start)
+	if errors.Is(err, ErrAIUnavailable) || isQuotaExceeded(err) {
+		return "", err // Provider tidak dipanggil, tidak ada yang perlu dicatat
+	}
     if err != nil {
+		rec.Outcome, rec.Err = repository.GenerationOutcomeProviderError, err
+		s.recordGeneration(ctx, rec)
 		return "", fmt.Errorf("gagal menghasilkan feedback dari AI: %w", err)
 	}
+	rec.Response, rec.Outcome = resp, repository.GenerationOutcomeOK
+	s.recordGeneration(ctx, rec)
 	
-	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-		return "Sepertinya ada sedikit masalah saat menghasilkan feedback, tapi tetap semangat untuk esok hari!", nil
+	if strings.TrimSpace(resp.Text) == "" {
+		return s.DefaultReviewFeedback(ctx), nil
 	}
 
-	aiResponse := resp.Candidates[0].Content.Parts[0].(genai.Text)
-	return string(aiResponse), nil
+	return resp.Text, nil
 }
\ No newline at end of file
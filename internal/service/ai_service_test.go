@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+const validRoadmapJSON = `[{"step_order":1,"title":"Pelajari dasar"},{"step_order":2,"title":"Buat proyek kecil"},{"step_order":3,"title":"Publikasikan hasil"}]`
+
+// scriptedReply adalah satu respons server chat completion palsu. status 0 berarti 200.
+type scriptedReply struct {
+	status  int
+	content string
+}
+
+// scriptedLLMServer menjawab setiap panggilan dengan reply berikutnya dan mencatat prompt yang
+// diterima. Reply terakhir dipakai ulang jika panggilan melebihi jumlah reply.
+type scriptedLLMServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	replies []scriptedReply
+	prompts []string
+}
+
+func newScriptedLLMServer(t *testing.T, replies ...scriptedReply) *scriptedLLMServer {
+	t.Helper()
+	s := &scriptedLLMServer{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+
+		s.mu.Lock()
+		reply := s.replies[min(len(s.prompts), len(s.replies)-1)]
+		s.prompts = append(s.prompts, req.Messages[0].Content)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if reply.status != 0 && reply.status != http.StatusOK {
+			w.WriteHeader(reply.status)
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"message": reply.content}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": reply.content}}},
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *scriptedLLMServer) calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.prompts...)
+}
+
+func newTestAIService(t *testing.T, server *scriptedLLMServer, env map[string]string) *AIService {
+	t.Helper()
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	return NewAIService(NewOpenAIProvider(server.URL, "", "test-model", server.Client()), nil, nil)
+}
+
+func TestGenerateRoadmapSchemaRepair(t *testing.T) {
+	tests := []struct {
+		name          string
+		replies       []scriptedReply
+		repairs       string
+		wantCalls     int
+		wantSteps     int
+		wantAttempts  int    // > 0 jika *AIOutputError diharapkan
+		wantInRepair  string // Potongan yang harus ada di prompt perbaikan pertama
+		wantErrSubstr string
+	}{
+		{
+			name:      "valid on first call",
+			replies:   []scriptedReply{{content: "Berikut roadmap-nya:\n```json\n" + validRoadmapJSON + "\n```"}},
+			wantCalls: 1,
+			wantSteps: 3,
+		},
+		{
+			name: "repaired after invalid json",
+			replies: []scriptedReply{
+				{content: `[{"step_order":1,"title":"Pelajari dasar",}]`},
+				{content: validRoadmapJSON},
+			},
+			wantCalls:    2,
+			wantSteps:    3,
+			wantInRepair: "JSON tidak valid",
+		},
+		{
+			name: "repaired after too few items",
+			replies: []scriptedReply{
+				{content: `[{"step_order":1,"title":"Satu-satunya langkah"}]`},
+				{content: validRoadmapJSON},
+			},
+			wantCalls:    2,
+			wantSteps:    3,
+			wantInRepair: "Satu-satunya langkah",
+		},
+		{
+			name:         "gives up after all repair attempts",
+			replies:      []scriptedReply{{content: "maaf, saya tidak bisa membantu"}},
+			wantCalls:    3,
+			wantAttempts: 3,
+			wantInRepair: "respons tidak mengandung JSON array",
+		},
+		{
+			name:         "repair disabled",
+			replies:      []scriptedReply{{content: "[]"}},
+			repairs:      "0",
+			wantCalls:    1,
+			wantAttempts: 1,
+		},
+		{
+			name:          "provider error is not repaired",
+			replies:       []scriptedReply{{status: http.StatusInternalServerError, content: "server sibuk"}},
+			wantCalls:     1,
+			wantErrSubstr: "server sibuk",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newScriptedLLMServer(t, tt.replies...)
+			ai := newTestAIService(t, server, map[string]string{"AI_MAX_REPAIR_ATTEMPTS": tt.repairs})
+
+			steps, err := ai.GenerateRoadmapWithAI(context.Background(), "Belajar Go", nil)
+			calls := server.calls()
+			if len(calls) != tt.wantCalls {
+				t.Fatalf("provider called %d times, want %d", len(calls), tt.wantCalls)
+			}
+			if tt.wantInRepair != "" && !strings.Contains(calls[1], tt.wantInRepair) {
+				t.Errorf("repair prompt does not mention %q:\n%s", tt.wantInRepair, calls[1])
+			}
+			for _, prompt := range calls[1:] {
+				if !strings.HasPrefix(prompt, calls[0]) {
+					t.Errorf("repair prompt does not start with the original prompt:\n%s", prompt)
+				}
+			}
+
+			switch {
+			case tt.wantAttempts > 0:
+				var outErr *AIOutputError
+				if !errors.As(err, &outErr) || outErr.Attempts != tt.wantAttempts || outErr.Kind != KindRoadmap {
+					t.Fatalf("err = %v, want *AIOutputError after %d attempts", err, tt.wantAttempts)
+				}
+			case tt.wantErrSubstr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErrSubstr)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(steps) != tt.wantSteps || steps[0].Order != 1 || steps[0].Title != "Pelajari dasar" {
+					t.Errorf("unexpected steps: %+v", steps)
+				}
+			}
+		})
+	}
+}
+
+func TestRoadmapWithFallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		replies    []scriptedReply
+		env        map[string]string
+		wantSource string
+		wantCalls  int
+		wantErr    bool
+	}{
+		{
+			name:       "ai succeeds",
+			replies:    []scriptedReply{{content: validRoadmapJSON}},
+			wantSource: repository.SourceAI,
+			wantCalls:  1,
+		},
+		{
+			name:       "provider error falls back to template",
+			replies:    []scriptedReply{{status: http.StatusServiceUnavailable, content: "overloaded"}},
+			wantSource: repository.SourceTemplate,
+			wantCalls:  1,
+		},
+		{
+			name:       "invalid output falls back to template",
+			replies:    []scriptedReply{{content: "bukan json"}},
+			wantSource: repository.SourceTemplate,
+			wantCalls:  3,
+		},
+		{
+			name:      "fallback disabled returns the error",
+			replies:   []scriptedReply{{status: http.StatusServiceUnavailable, content: "overloaded"}},
+			env:       map[string]string{"AI_FALLBACK_ENABLED": "false"},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newScriptedLLMServer(t, tt.replies...)
+			ai := newTestAIService(t, server, tt.env)
+
+			steps, source, err := ai.RoadmapWithFallback(context.Background(), "Belajar Go", nil)
+			if got := len(server.calls()); got != tt.wantCalls {
+				t.Errorf("provider called %d times, want %d", got, tt.wantCalls)
+			}
+			if tt.wantErr {
+				if err == nil || steps != nil || source != "" {
+					t.Fatalf("got steps %v source %q err %v, want an error", steps, source, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if source != tt.wantSource || len(steps) == 0 {
+				t.Errorf("got %d steps from %q, want steps from %q", len(steps), source, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestOpenBreakerSkipsProvider(t *testing.T) {
+	tests := []struct {
+		name       string
+		fallback   string
+		wantSource string
+	}{
+		{name: "fallback serves template", wantSource: repository.SourceTemplate},
+		{name: "fallback disabled returns unavailable", fallback: "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newScriptedLLMServer(t, scriptedReply{status: http.StatusServiceUnavailable, content: "overloaded"})
+			ai := newTestAIService(t, server, map[string]string{
+				"AI_BREAKER_FAILURE_THRESHOLD": "1",
+				"AI_BREAKER_COOLDOWN_SECONDS":  "60",
+				"AI_FALLBACK_ENABLED":          tt.fallback,
+			})
+
+			// Kegagalan pertama membuka breaker
+			ai.RoadmapWithFallback(context.Background(), "Belajar Go", nil)
+			if state := ai.Health().Circuit.State; state != CircuitOpen {
+				t.Fatalf("circuit state = %q after a failure, want %q", state, CircuitOpen)
+			}
+
+			steps, source, err := ai.RoadmapWithFallback(context.Background(), "Belajar Go", nil)
+			if got := len(server.calls()); got != 1 {
+				t.Errorf("provider called %d times, want 1 (the open breaker must not call it)", got)
+			}
+			if tt.wantSource == "" {
+				var unavailable *AIUnavailableError
+				if !errors.Is(err, ErrAIUnavailable) || !errors.As(err, &unavailable) || unavailable.RetryAfter <= 0 {
+					t.Fatalf("err = %v, want *AIUnavailableError with a retry delay", err)
+				}
+				return
+			}
+			if err != nil || source != tt.wantSource || len(steps) == 0 {
+				t.Errorf("got %d steps from %q (err %v), want steps from %q", len(steps), source, err, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestQuotaExceededSkipsProvider(t *testing.T) {
+	exhausted := &QuotaExceededError{Period: "daily", Limit: 3, RetryAfter: time.Hour}
+	tests := []struct {
+		name string
+		call func(ctx context.Context, ai *AIService) error
+	}{
+		{
+			name: "roadmap does not fall back",
+			call: func(ctx context.Context, ai *AIService) error {
+				_, _, err := ai.RoadmapWithFallback(ctx, "Belajar Go", nil)
+				return err
+			},
+		},
+		{
+			name: "review feedback returns the quota error as is",
+			call: func(ctx context.Context, ai *AIService) error {
+				_, err := ai.GenerateReviewFeedback(ctx, "Belajar Go", []repository.TaskSummary{{Status: "completed", Count: 2}})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newScriptedLLMServer(t, scriptedReply{content: validRoadmapJSON})
+			ai := newTestAIService(t, server, nil)
+			ctx := context.WithValue(context.Background(), quotaChargeKey{}, &quotaCharge{
+				consume: func(context.Context) error { return exhausted },
+			})
+
+			err := tt.call(ctx, ai)
+			if err != exhausted {
+				t.Fatalf("err = %v, want the unwrapped *QuotaExceededError", err)
+			}
+			if got := len(server.calls()); got != 0 {
+				t.Errorf("provider called %d times, want 0", got)
+			}
+			if state := ai.Health().Circuit; state.State != CircuitClosed || state.ConsecutiveFailures != 0 {
+				t.Errorf("quota errors must not count as provider failures, got %+v", state)
+			}
+		})
+	}
+}
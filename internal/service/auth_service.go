@@ -2,24 +2,93 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"log"
+	netmail "net/mail"
+	"strings"
 	"time"
 
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/auth"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/mail"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// TokenPair adalah hasil login atau refresh: access token JWT berumur pendek dan
+// refresh token opaque untuk memperpanjang sesi.
+type TokenPair struct {
+	Token        string `json:"token"` // Sama dengan access_token, dipertahankan untuk klien lama
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // Umur access token dalam detik
+}
+
 type AuthService struct {
-	userRepo *repository.UserRepository
+	db          *pgxpool.Pool
+	userRepo    *repository.UserRepository
+	refreshRepo *repository.RefreshTokenRepository
+	accessTTL   time.Duration // Umur access token
+	refreshTTL  time.Duration // Umur refresh token sejak diterbitkan (rotasi menerbitkan token baru)
+	tokenStates *tokenStateCache
+	keys        *auth.KeySet // Kunci penandatangan access token
+
+	accountTokens *repository.AccountTokenRepository
+	loginAttempts *repository.LoginAttemptRepository
+	throttle      loginThrottle
+	mailer        mail.Mailer
+	appBaseURL    string        // URL frontend untuk link di email
+	verifyTTL     time.Duration // Umur link verifikasi email
+	resetTTL      time.Duration // Umur link reset password
+	requireVerify bool          // Tolak login sebelum email diverifikasi
 }
 
-func NewAuthService(userRepo *repository.UserRepository) *AuthService {
-	return &AuthService{userRepo: userRepo}
+func NewAuthService(db *pgxpool.Pool, userRepo *repository.UserRepository, refreshRepo *repository.RefreshTokenRepository, accountTokens *repository.AccountTokenRepository, loginAttempts *repository.LoginAttemptRepository, keys *auth.KeySet, mailer mail.Mailer) *AuthService {
+	appBaseURL := strings.TrimRight(config.Get("APP_BASE_URL"), "/")
+	if appBaseURL == "" {
+		appBaseURL = "http://localhost:3000"
+	}
+	return &AuthService{
+		db:            db,
+		userRepo:      userRepo,
+		refreshRepo:   refreshRepo,
+		keys:          keys,
+		accessTTL:     time.Duration(intFromConfig("JWT_ACCESS_TOKEN_TTL_MINUTES", 15)) * time.Minute,
+		refreshTTL:    time.Duration(intFromConfig("JWT_REFRESH_TOKEN_TTL_DAYS", 30)) * 24 * time.Hour,
+		tokenStates:   newTokenStateCache(time.Duration(intFromConfig("JWT_REVOCATION_CACHE_SECONDS", 30)) * time.Second),
+		accountTokens: accountTokens,
+		loginAttempts: loginAttempts,
+		throttle:      newLoginThrottleFromConfig(),
+		mailer:        mailer,
+		appBaseURL:    appBaseURL,
+		verifyTTL:     time.Duration(intFromConfig("EMAIL_VERIFICATION_TTL_HOURS", 48)) * time.Hour,
+		resetTTL:      time.Duration(intFromConfig("PASSWORD_RESET_TTL_MINUTES", 60)) * time.Minute,
+		requireVerify: config.Get("AUTH_REQUIRE_EMAIL_VERIFICATION") == "true",
+	}
 }
 
+// RegisterUser membuat akun baru lalu mengirim email verifikasi.
 func (s *AuthService) RegisterUser(ctx context.Context, email, password string) (*repository.User, error) {
+	// Hanya alamat email polos yang diterima (bukan "Nama <alamat>")
+	email = strings.TrimSpace(email)
+	if addr, err := netmail.ParseAddress(email); err != nil || addr.Address != email {
+		return nil, ErrInvalidEmail
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -37,65 +106,304 @@ func (s *AuthService) RegisterUser(ctx context.Context, email, password string)
 	}
 
 	newUser.ID = id
+	newUser.Locale = DefaultLocale
+	s.sendVerificationAfterRegister(ctx, newUser)
 	return newUser, nil
 }
 
-func (s *AuthService) LoginUser(ctx context.Context, email, password string) (string, error) {
-	// 1. Cari user berdasarkan email
+// LoginUser memverifikasi kredensial lalu menerbitkan access token dan refresh token
+// yang memulai family (sesi) baru. userAgent hanya disimpan sebagai keterangan sesi.
+// Setiap percobaan dicatat; setelah beberapa kali gagal untuk akun atau IP yang sama,
+// login ditahan dengan *LoginThrottledError tanpa memeriksa password.
+func (s *AuthService) LoginUser(ctx context.Context, email, password, userAgent, ipAddress string) (*TokenPair, error) {
+	// 1. Tahan login jika akun atau IP ini baru saja terlalu sering gagal; jika tidak,
+	// percobaan ini langsung dicatat sebagai gagal sampai terbukti berhasil
+	attempt := &repository.LoginAttempt{Email: normalizeLoginEmail(email), IPAddress: ipAddress, UserAgent: userAgent}
+	if err := s.reserveLoginAttempt(ctx, attempt); err != nil {
+		return nil, err
+	}
+
+	// 2. Cari user berdasarkan email
 	user, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
-		// Jika user tidak ditemukan, kembalikan error yang jelas
-		return "", errors.New("invalid credentials")
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		// Jika user tidak ditemukan, tetap jalankan bcrypt agar waktu respons sama
+		compareDummyPassword(password)
+		return nil, ErrInvalidCredentials
 	}
+	attempt.UserID = &user.ID
+	defer s.finishLoginAttempt(ctx, attempt)
 
-	// 2. Bandingkan password yang diberikan dengan hash di database
+	// 3. Bandingkan password yang diberikan dengan hash di database
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
 		// Jika password salah, bcrypt akan mengembalikan error
-		return "", errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+	if user.LockedAt != nil {
+		return nil, auth.ErrAccountLocked
+	}
+	if s.requireVerify && user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
 	}
+	// Hanya login yang benar-benar diterima yang mengatur ulang hitungan kegagalan akun ini
+	attempt.Succeeded = true
 
-	// 3. Jika berhasil, buat JWT Token
-	claims := jwt.MapClaims{
-		"sub": user.ID,                                       // Subject (identitas user)
-		"exp": time.Now().UTC().Add(time.Hour * 24).Unix(),         // Waktu kedaluwarsa (24 jam)
-		"iat": time.Now().UTC().Unix(),                             // Waktu token dibuat
+	// 4. Jika berhasil, terbitkan pasangan token untuk sesi baru
+	return s.issueTokens(ctx, user.ID, user.TokenVersion, "", userAgent, nil)
+}
+
+// RefreshTokens menukar refresh token dengan pasangan token baru (rotasi). Refresh token
+// yang sudah pernah ditukar tidak bisa dipakai lagi; jika tetap dipakai, seluruh family-nya
+// dicabut karena token tersebut kemungkinan sudah bocor.
+func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken, userAgent string) (*TokenPair, error) {
+	current, err := s.refreshRepo.GetRefreshTokenByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if current.UsedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, current)
+	}
+	if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	version, locked, err := s.userRepo.GetTokenState(ctx, current.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		return nil, auth.ErrAccountLocked
+	}
+
+	if userAgent == "" {
+		userAgent = current.UserAgent
+	}
+	pair, err := s.issueTokens(ctx, current.UserID, version, current.FamilyID, userAgent, &current.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Request lain menukar token yang sama lebih dulu
+		return nil, s.revokeReusedFamily(ctx, current)
+	}
+	return pair, err
+}
+
+func (s *AuthService) revokeReusedFamily(ctx context.Context, token *repository.RefreshToken) error {
+	log.Printf("Refresh token dipakai ulang untuk user %s, mencabut family %s", token.UserID, token.FamilyID)
+	if err := s.refreshRepo.RevokeFamily(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
 
-	// Ambil secret key dari .env
-	jwtSecret := config.Get("JWT_SECRET_KEY")
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+// Logout mencabut sesi milik refresh token tersebut. Token yang tidak dikenal diabaikan
+// agar logout selalu berhasil dari sisi klien.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	token, err := s.refreshRepo.GetRefreshTokenByHash(ctx, hashRefreshToken(refreshToken))
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	return s.refreshRepo.RevokeFamily(ctx, token.FamilyID)
+}
+
+// LogoutAll mencabut semua sesi user di semua perangkat. Access token yang sudah terbit
+// tetap berlaku sampai kedaluwarsa (lihat JWT_ACCESS_TOKEN_TTL_MINUTES).
+func (s *AuthService) LogoutAll(ctx context.Context, userID string) error {
+	return s.refreshRepo.RevokeUserTokens(ctx, userID)
+}
+
+// ValidateTokenVersion dipakai JwtMiddleware untuk menolak access token yang sudah dicabut.
+// Status token user di-cache selama JWT_REVOCATION_CACHE_SECONDS agar tidak ada query per request.
+func (s *AuthService) ValidateTokenVersion(ctx context.Context, userID string, version int) error {
+	now := time.Now()
+	state, ok := s.tokenStates.get(userID, now)
+	if !ok {
+		generation := s.tokenStates.currentGeneration()
+		current, locked, err := s.userRepo.GetTokenState(ctx, userID)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			state = tokenState{missing: true}
+		case err != nil:
+			return err
+		default:
+			state = tokenState{version: current, locked: locked}
+		}
+		s.tokenStates.put(userID, state, generation, now)
+	}
+
+	switch {
+	case state.missing:
+		return auth.ErrTokenRevoked
+	case state.locked:
+		return auth.ErrAccountLocked
+	case state.version != version:
+		return auth.ErrTokenRevoked
+	}
+	return nil
+}
+
+// SetAccountLocked mengunci atau membuka akun (dipakai oleh perintah admin `user lock|unlock`).
+// Mengunci akun mencabut semua access token dan refresh token user.
+func (s *AuthService) SetAccountLocked(ctx context.Context, email string, locked bool) error {
+	userID, err := s.userRepo.SetUserLocked(ctx, email, locked)
+	if err != nil {
+		return err
+	}
+	s.tokenStates.invalidate(userID)
+	if !locked {
+		return nil
+	}
+	return s.refreshRepo.RevokeUserTokens(ctx, userID)
+}
+
+// DeleteAccount menghapus akun user setelah password dikonfirmasi. Semua data user, termasuk
+// refresh token, ikut terhapus dan access token yang masih beredar langsung ditolak.
+func (s *AuthService) DeleteAccount(ctx context.Context, userID, password string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+	if err := s.userRepo.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	s.tokenStates.invalidate(userID)
+	return nil
+}
+
+// issueTokens membuat access token dan refresh token baru. version adalah token_version user
+// saat ini; familyID kosong memulai family baru; rotateFrom diisi untuk menandai token lama
+// terpakai dalam transaksi yang sama.
+func (s *AuthService) issueTokens(ctx context.Context, userID string, version int, familyID, userAgent string, rotateFrom *string) (*TokenPair, error) {
+	now := time.Now().UTC()
+	claims := jwt.MapClaims{
+		"sub":                  userID,                      // Subject (identitas user)
+		"exp":                  now.Add(s.accessTTL).Unix(), // Waktu kedaluwarsa
+		"iat":                  now.Unix(),                  // Waktu token dibuat
+		auth.TokenVersionClaim: version,                     // Dicocokkan dengan users.token_version oleh middleware
+	}
+	// Ditandatangani kunci yang sedang aktif (lihat JWT_KEYS); kid dicantumkan di header
+	accessToken, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	record := &repository.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(refreshToken),
+		UserAgent: userAgent,
+		ExpiresAt: now.Add(s.refreshTTL),
+	}
+	if rotateFrom != nil {
+		err = s.refreshRepo.RotateRefreshToken(ctx, *rotateFrom, record)
+	} else {
+		err = s.refreshRepo.CreateRefreshToken(ctx, record)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		Token:        accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTTL.Seconds()),
+	}, nil
+}
+
+// newRefreshToken membuat token opaque acak 256-bit.
+func newRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
 		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
 
-	return tokenString, nil
+// hashRefreshToken menghasilkan hash yang disimpan di database. Token sudah acak dengan
+// entropi tinggi, jadi SHA-256 cukup (tidak perlu bcrypt) dan bisa dipakai untuk lookup.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func (s *AuthService) ChangePassword(ctx context.Context, userID, oldPassword, newPassword string) error {
+// ChangePassword mengganti password dan mencabut semua sesi lama (access token dan refresh
+// token) dalam satu transaksi. Perangkat yang mengganti password langsung menerima pasangan
+// token baru.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, oldPassword, newPassword, userAgent string) (*TokenPair, error) {
+    if strings.TrimSpace(newPassword) == "" {
+        return nil, ErrPasswordRequired
+    }
+
     user, err := s.userRepo.GetUserByID(ctx, userID)
     if err != nil {
-        return errors.New("user not found")
+        return nil, err
     }
 
     // Bandingkan password lama yang diinput dengan yang ada di DB
     if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(oldPassword)); err != nil {
-        return errors.New("invalid old password")
+        return nil, ErrInvalidCredentials
     }
 
-    // Hash password baru
+    // Hash password baru di luar transaksi agar bcrypt tidak menahan koneksi database
     newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
     if err != nil {
-        return err
+        return nil, err
     }
 
-    // Simpan hash password baru
-    return s.userRepo.UpdatePasswordHash(ctx, userID, string(newHashedPassword))
+    // Simpan hash password baru; token_version ikut naik sehingga access token lama ditolak.
+    // Refresh token dicabut di transaksi yang sama agar password tidak berganti tanpa sesi lama ikut dicabut.
+    var version int
+    err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+        var err error
+        if version, err = s.userRepo.WithTx(tx).UpdatePasswordHash(ctx, userID, string(newHashedPassword)); err != nil {
+            return err
+        }
+        return s.refreshRepo.WithTx(tx).RevokeUserTokens(ctx, userID)
+    })
+    if err != nil {
+        return nil, err
+    }
+    s.tokenStates.invalidate(userID)
+    return s.issueTokens(ctx, userID, version, "", userAgent, nil)
+}
+
+// ErrUnsupportedLocale dikembalikan ketika user memilih bahasa yang belum punya template prompt.
+var ErrUnsupportedLocale = errors.New("unsupported locale")
+
+// UpdateLocale menyimpan preferensi bahasa user untuk konten AI.
+func (s *AuthService) UpdateLocale(ctx context.Context, userID, locale string) error {
+	if !IsSupportedLocale(locale) {
+		return ErrUnsupportedLocale
+	}
+	return s.userRepo.UpdateLocale(ctx, userID, locale)
 }
 
 func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*repository.User, error) {
-	// Service ini hanya meneruskan panggilan ke repository.
-	return s.userRepo.GetUserByID(ctx, userID)
-}
\ No newline at end of file
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Tampilkan streak yang masih berlaku hari ini (streak yang putus baru disimpan saat review berikutnya)
+	user.CurrentStreak = effectiveStreak(&repository.StreakState{
+		CurrentStreak:  user.CurrentStreak,
+		LastStreakDate: user.LastStreakDate,
+		Freezes:        user.StreakFreezes,
+	}, time.Now())
+	return user, nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrAIUnavailable dikembalikan tanpa memanggil provider ketika circuit breaker sedang terbuka.
+var ErrAIUnavailable = errors.New("layanan AI sedang tidak tersedia")
+
+// AIUnavailableError membawa sisa waktu cooldown breaker. errors.Is(err, ErrAIUnavailable) bernilai true.
+type AIUnavailableError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *AIUnavailableError) Error() string {
+	return fmt.Sprintf("%v (provider %s, coba lagi dalam %s)", ErrAIUnavailable, e.Provider, e.RetryAfter.Round(time.Second))
+}
+
+func (e *AIUnavailableError) Unwrap() error {
+	return ErrAIUnavailable
+}
+
+// Status circuit breaker.
+const (
+	CircuitClosed   = "closed"    // Normal, semua panggilan diteruskan
+	CircuitOpen     = "open"      // Provider dianggap mati, panggilan langsung ditolak
+	CircuitHalfOpen = "half_open" // Masa cooldown habis, satu panggilan percobaan diizinkan
+)
+
+// CircuitBreakerStatus adalah potret state breaker untuk endpoint health.
+type CircuitBreakerStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	FailureThreshold    int        `json:"failure_threshold"`
+	CooldownSeconds     int        `json:"cooldown_seconds"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAfterSeconds   int        `json:"retry_after_seconds,omitempty"`
+	LastError           string     `json:"-"` // Hanya untuk log; endpoint health bisa diakses tanpa login
+}
+
+// CircuitBreaker membuka sirkuit setelah `threshold` kegagalan berturut-turut. Selama terbuka
+// semua panggilan ditolak dengan ErrAIUnavailable; setelah cooldown, satu panggilan percobaan
+// diteruskan dan hasilnya menentukan apakah sirkuit ditutup kembali atau dibuka lagi.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool // Ada panggilan percobaan yang sedang berjalan saat half-open
+	lastErr  string
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     CircuitClosed,
+	}
+}
+
+// Allow memeriksa apakah panggilan ke provider boleh dilakukan.
+// Setiap Allow yang berhasil harus diikuti RecordSuccess, RecordFailure, atau RecordIgnored.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrAIUnavailable
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrAIUnavailable
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// RecordSuccess menutup sirkuit dan mereset hitungan kegagalan.
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = CircuitClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// RecordFailure mencatat kegagalan provider dan membuka sirkuit bila perlu.
+func (b *CircuitBreaker) RecordFailure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if err != nil {
+		b.lastErr = err.Error()
+	}
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+	}
+	b.probing = false
+}
+
+// RecordIgnored melepas slot percobaan tanpa mengubah state, misalnya ketika
+// klien membatalkan request sebelum provider sempat menjawab.
+func (b *CircuitBreaker) RecordIgnored() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// Status mengembalikan state breaker saat ini.
+func (b *CircuitBreaker) Status() CircuitBreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := CircuitBreakerStatus{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		FailureThreshold:    b.threshold,
+		CooldownSeconds:     int(b.cooldown.Seconds()),
+		LastError:           b.lastErr,
+	}
+	if b.state != CircuitClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	if b.state == CircuitOpen {
+		if remaining := b.cooldown - b.now().Sub(b.openedAt); remaining > 0 {
+			status.RetryAfterSeconds = int(remaining.Round(time.Second).Seconds())
+		}
+	}
+	return status
+}
+
+// RetryAfter adalah sisa waktu cooldown; 0 jika sirkuit tidak sedang terbuka.
+func (b *CircuitBreaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != CircuitOpen {
+		return 0
+	}
+	if remaining := b.cooldown - b.now().Sub(b.openedAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// DailyTaskContext merangkum apa yang sudah dikerjakan user agar AI bisa
+// menyesuaikan tugas hari ini. Semua field boleh kosong (misalnya di hari pertama).
+type DailyTaskContext struct {
+	Days         []DayTaskHistory             // Hari-hari sebelumnya, yang terlama lebih dulu
+	CarriedOver  []string                     // Tugas kemarin yang terlewat / belum selesai
+	StepProgress *repository.StepTaskProgress // Progres tugas pada langkah roadmap yang sedang aktif
+	LastFeedback string                       // Feedback coach dari review terakhir
+}
+
+// DayTaskHistory adalah daftar tugas pada satu tanggal.
+type DayTaskHistory struct {
+	Date  string
+	Tasks []TaskOutcome
+}
+
+type TaskOutcome struct {
+	Title  string
+	Status string
+}
+
+// HasHistory dipakai template prompt untuk membedakan hari pertama.
+func (c *DailyTaskContext) HasHistory() bool {
+	return c != nil && (len(c.Days) > 0 || c.LastFeedback != "")
+}
+
+const maxFeedbackContextRunes = 500
+
+// buildDailyTaskContext mengumpulkan riwayat tugas `days` hari terakhir, progres langkah
+// roadmap saat ini, dan feedback review terakhir sebelum targetDate.
+func (s *TaskService) buildDailyTaskContext(ctx context.Context, userID, stepID string, targetDate time.Time, days int) (*DailyTaskContext, error) {
+	dailyCtx := &DailyTaskContext{}
+
+	yesterday := targetDate.AddDate(0, 0, -1)
+	from := targetDate.AddDate(0, 0, -days)
+	tasks, err := s.taskRepo.GetTasksBetween(ctx, userID, from, yesterday)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		date := task.ScheduledDate.Format("2006-01-02")
+		if n := len(dailyCtx.Days); n == 0 || dailyCtx.Days[n-1].Date != date {
+			dailyCtx.Days = append(dailyCtx.Days, DayTaskHistory{Date: date})
+		}
+		day := &dailyCtx.Days[len(dailyCtx.Days)-1]
+		day.Tasks = append(day.Tasks, TaskOutcome{Title: task.Title, Status: task.Status})
+
+		if date == yesterday.Format("2006-01-02") && task.Status != "completed" {
+			dailyCtx.CarriedOver = append(dailyCtx.CarriedOver, task.Title)
+		}
+	}
+
+	if stepID != "" {
+		progress, err := s.taskRepo.GetStepTaskProgress(ctx, stepID)
+		if err != nil {
+			return nil, err
+		}
+		dailyCtx.StepProgress = progress
+	}
+
+	review, err := s.reviewRepo.GetLatestReviewBefore(ctx, userID, targetDate)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if review != nil {
+		dailyCtx.LastFeedback = shortenForTitle(review.AIFeedback, maxFeedbackContextRunes)
+	}
+
+	return dailyCtx, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+// Judul template cadangan per locale. %s diisi deskripsi goal / judul langkah.
+var fallbackRoadmapTitles = map[string][]string{
+	LocaleIndonesian: {
+		"Riset: pelajari apa saja yang dibutuhkan untuk \"%s\"",
+		"Rencanakan: susun target mingguan dan sumber daya yang diperlukan",
+		"Eksekusi: kerjakan rencana secara konsisten setiap hari",
+		"Review: evaluasi hasil dan sesuaikan langkah berikutnya",
+	},
+	LocaleEnglish: {
+		"Research: learn what it takes to \"%s\"",
+		"Plan: set weekly targets and gather the resources you need",
+		"Execute: work through the plan consistently every day",
+		"Review: evaluate the results and adjust the next steps",
+	},
+}
+
+var fallbackTaskTitles = map[string][]string{
+	LocaleIndonesian: {
+		"Tentukan hasil konkret hari ini untuk: %s",
+		"Kerjakan fokus 45 menit pada: %s",
+		"Catat progres dan hambatan hari ini",
+	},
+	LocaleEnglish: {
+		"Define a concrete outcome for today on: %s",
+		"Do a focused 45-minute session on: %s",
+		"Write down today's progress and blockers",
+	},
+}
+
+// fallbackRoadmap membuat roadmap generik (riset / rencana / eksekusi / review)
+// ketika AI tidak bisa dipakai. Hasilnya deterministik untuk deskripsi yang sama.
+func fallbackRoadmap(locale, goalDescription string) []repository.RoadmapStep {
+	titles := localizedTitles(fallbackRoadmapTitles, locale, shortenForTitle(goalDescription, 80))
+
+	steps := make([]repository.RoadmapStep, len(titles))
+	for i, title := range titles {
+		steps[i] = repository.RoadmapStep{Order: i + 1, Title: title}
+	}
+	return steps
+}
+
+// fallbackDailyTasks menurunkan tugas harian sederhana dari judul langkah roadmap yang sedang aktif.
+func fallbackDailyTasks(locale, stepTitle string) []repository.Task {
+	titles := localizedTitles(fallbackTaskTitles, locale, shortenForTitle(stepTitle, 100))
+
+	tasks := make([]repository.Task, len(titles))
+	for i, title := range titles {
+		tasks[i] = repository.Task{Title: title}
+	}
+	return tasks
+}
+
+// localizedTitles memilih judul sesuai locale dan mengisi placeholder %s dengan arg.
+func localizedTitles(byLocale map[string][]string, locale, arg string) []string {
+	formats, ok := byLocale[locale]
+	if !ok {
+		formats = byLocale[DefaultLocale]
+	}
+	titles := make([]string, len(formats))
+	for i, format := range formats {
+		if strings.Contains(format, "%s") {
+			titles[i] = fmt.Sprintf(format, arg)
+		} else {
+			titles[i] = format
+		}
+	}
+	return titles
+}
+
+// shortenForTitle memotong teks agar judul tetap muat di kolom VARCHAR(255).
+func shortenForTitle(text string, maxRunes int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= maxRunes {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:maxRunes-3])) + "..."
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// GeminiProvider memanggil Google Gemini melalui SDK genai. Streaming memakai endpoint REST
+// dengan alt=sse secara langsung: stream JSON array milik SDK berhenti dengan error pada
+// akhir array jika encoding/json berbasis v2 (Go 1.25+ dengan GOEXPERIMENT=jsonv2).
+type GeminiProvider struct {
+	model *genai.GenerativeModel
+
+	baseURL    string
+	apiKey     string
+	modelName  string // Selalu berawalan "models/"
+	httpClient *http.Client
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature float64 `json:"temperature"`
+}
+
+// geminiStreamRequest adalah body streamGenerateContent yang kita kirim.
+type geminiStreamRequest struct {
+	Contents         []geminiContent        `json:"contents"`
+	GenerationConfig geminiGenerationConfig `json:"generationConfig"`
+}
+
+// geminiStreamChunk adalah satu event "data:" dari streamGenerateContent?alt=sse.
+type geminiStreamChunk struct {
+	Candidates []struct {
+		Content *geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// geminiBaseURL adalah endpoint REST Gemini bawaan.
+const geminiBaseURL = "https://generativelanguage.googleapis.com"
+
+func NewGeminiProvider(ctx context.Context, apiKey, modelName string) (*GeminiProvider, error) {
+	return newGeminiProvider(ctx, geminiBaseURL, apiKey, modelName)
+}
+
+// newGeminiProvider memungkinkan test mengarahkan request ke server lokal.
+func newGeminiProvider(ctx context.Context, baseURL, apiKey, modelName string) (*GeminiProvider, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey), option.WithEndpoint(baseURL))
+	if err != nil {
+		return nil, err
+	}
+
+	model := client.GenerativeModel(modelName)
+	model.SetTemperature(0.7)
+	if !strings.HasPrefix(modelName, "models/") {
+		modelName = "models/" + modelName
+	}
+	return &GeminiProvider{
+		model:      model,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		modelName:  modelName,
+		httpClient: &http.Client{Timeout: 120 * time.Second},
+	}, nil
+}
+
+func (p *GeminiProvider) Name() string {
+	return "gemini"
+}
+
+func (p *GeminiProvider) Generate(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	resp, err := p.model.GenerateContent(ctx, genai.Text(req.Prompt))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("respons AI tidak valid atau kosong")
+	}
+
+	// Gabungkan semua bagian teks dari kandidat pertama
+	var sb strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			sb.WriteString(string(text))
+		}
+	}
+	result := &LLMResponse{Text: sb.String()}
+	if resp.UsageMetadata != nil {
+		result.PromptTokens = int(resp.UsageMetadata.PromptTokenCount)
+		result.CompletionTokens = int(resp.UsageMetadata.CandidatesTokenCount)
+		result.TotalTokens = int(resp.UsageMetadata.TotalTokenCount)
+	}
+	return result, nil
+}
+
+// GenerateStream membaca respons server-sent events dari streamGenerateContent?alt=sse.
+// Setiap event berisi satu GenerateContentResponse; metadata token ada di event terakhir.
+func (p *GeminiProvider) GenerateStream(ctx context.Context, req LLMRequest, onChunk func(chunk string) error) (*LLMResponse, error) {
+	body, err := json.Marshal(geminiStreamRequest{
+		Contents:         []geminiContent{{Role: "user", Parts: []geminiPart{{Text: req.Prompt}}}},
+		GenerationConfig: geminiGenerationConfig{Temperature: 0.7},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := p.baseURL + "/v1beta/" + p.modelName + ":streamGenerateContent?alt=sse"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		var chunk geminiStreamChunk
+		if json.Unmarshal(respBody, &chunk) == nil && chunk.Error != nil && chunk.Error.Message != "" {
+			return nil, fmt.Errorf("gemini stream gagal (HTTP %d): %s", resp.StatusCode, chunk.Error.Message)
+		}
+		return nil, fmt.Errorf("gemini stream gagal (HTTP %d)", resp.StatusCode)
+	}
+
+	result := &LLMResponse{}
+	var sb strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var chunk geminiStreamChunk
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &chunk); err != nil {
+			return nil, fmt.Errorf("potongan stream gemini tidak valid: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("gemini stream gagal: %s", chunk.Error.Message)
+		}
+		if chunk.UsageMetadata != nil {
+			result.PromptTokens = chunk.UsageMetadata.PromptTokenCount
+			result.CompletionTokens = chunk.UsageMetadata.CandidatesTokenCount
+			result.TotalTokens = chunk.UsageMetadata.TotalTokenCount
+		}
+		if len(chunk.Candidates) == 0 || chunk.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			if part.Text == "" {
+				continue
+			}
+			sb.WriteString(part.Text)
+			if err := onChunk(part.Text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Text = sb.String()
+	return result, nil
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestGeminiProvider(t *testing.T, handler http.HandlerFunc) *GeminiProvider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	provider, err := newGeminiProvider(context.Background(), server.URL, "test-key", "gemini-test")
+	if err != nil {
+		t.Fatalf("newGeminiProvider: %v", err)
+	}
+	return provider
+}
+
+func TestGeminiProviderGenerate(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantText   string
+		wantTokens int
+		wantErr    string
+	}{
+		{
+			name:       "success joins all parts",
+			status:     http.StatusOK,
+			body:       `{"candidates":[{"content":{"role":"model","parts":[{"text":"[{\"title\":"},{"text":"\"Belajar\"}]"}]}}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":4,"totalTokenCount":12}}`,
+			wantText:   `[{"title":"Belajar"}]`,
+			wantTokens: 12,
+		},
+		{
+			name:     "success without usage",
+			status:   http.StatusOK,
+			body:     `{"candidates":[{"content":{"role":"model","parts":[{"text":"halo"}]}}]}`,
+			wantText: "halo",
+		},
+		{
+			name:    "no candidates",
+			status:  http.StatusOK,
+			body:    `{"candidates":[]}`,
+			wantErr: "respons AI tidak valid atau kosong",
+		},
+		{
+			name:    "candidate without parts",
+			status:  http.StatusOK,
+			body:    `{"candidates":[{"content":{"role":"model","parts":[]}}]}`,
+			wantErr: "respons AI tidak valid atau kosong",
+		},
+		{
+			name:    "api error",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error":{"code":429,"message":"quota exhausted","status":"RESOURCE_EXHAUSTED"}}`,
+			wantErr: "quota exhausted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestGeminiProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/models/gemini-test:generateContent") {
+					t.Errorf("path = %q, want .../models/gemini-test:generateContent", r.URL.Path)
+				}
+				if key := r.URL.Query().Get("key"); key != "test-key" {
+					t.Errorf("api key = %q, want test-key", key)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			resp, err := provider.Generate(context.Background(), LLMRequest{Kind: KindRoadmap, Prompt: "buat roadmap"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Text != tt.wantText || resp.TotalTokens != tt.wantTokens {
+				t.Errorf("got text %q tokens %d, want %q tokens %d", resp.Text, resp.TotalTokens, tt.wantText, tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestGeminiProviderGenerateStream(t *testing.T) {
+	stopErr := errors.New("klien terputus")
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		onChunkErr error
+		wantChunks []string
+		wantTokens int
+		wantErr    string
+	}{
+		{
+			name:   "chunks with usage in last event",
+			status: http.StatusOK,
+			body: "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Progres \"}]}}]}\n\n" +
+				"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"\"},{\"text\":\"yang bagus\"}]}}]," +
+				"\"usageMetadata\":{\"promptTokenCount\":3,\"candidatesTokenCount\":4,\"totalTokenCount\":7}}\n\n",
+			wantChunks: []string{"Progres ", "yang bagus"},
+			wantTokens: 7,
+		},
+		{
+			name:    "invalid event",
+			status:  http.StatusOK,
+			body:    "data: {\"candidates\":[]}\n\ndata: {bukan json\n\n",
+			wantErr: "potongan stream gemini tidak valid",
+		},
+		{
+			name:    "api error",
+			status:  http.StatusBadRequest,
+			body:    `{"error":{"code":400,"message":"API key not valid","status":"INVALID_ARGUMENT"}}`,
+			wantErr: "HTTP 400): API key not valid",
+		},
+		{
+			name:       "onChunk error stops the stream",
+			status:     http.StatusOK,
+			body:       "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"a\"}]}}]}\n\ndata: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"b\"}]}}]}\n\n",
+			onChunkErr: stopErr,
+			wantErr:    stopErr.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestGeminiProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1beta/models/gemini-test:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+					t.Errorf("url = %q, want /v1beta/models/gemini-test:streamGenerateContent?alt=sse", r.URL)
+				}
+				if key := r.Header.Get("x-goog-api-key"); key != "test-key" {
+					t.Errorf("api key = %q, want test-key", key)
+				}
+				var req geminiStreamRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Contents) != 1 || req.Contents[0].Parts[0].Text != "review" {
+					t.Errorf("unexpected request body %+v (err %v)", req, err)
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			var chunks []string
+			resp, err := provider.GenerateStream(context.Background(), LLMRequest{Kind: KindReviewFeedback, Prompt: "review"}, func(chunk string) error {
+				chunks = append(chunks, chunk)
+				return tt.onChunkErr
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				if tt.onChunkErr != nil && len(chunks) != 1 {
+					t.Errorf("onChunk called %d times after returning an error, want 1", len(chunks))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(chunks, "|") != strings.Join(tt.wantChunks, "|") {
+				t.Errorf("chunks = %q, want %q", chunks, tt.wantChunks)
+			}
+			if resp.Text != strings.Join(tt.wantChunks, "") || resp.TotalTokens != tt.wantTokens {
+				t.Errorf("got text %q tokens %d", resp.Text, resp.TotalTokens)
+			}
+		})
+	}
+}
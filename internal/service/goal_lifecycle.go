@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+var ErrInvalidGoalStatus = errors.New("status goal tidak dikenal")
+
+// GoalTransitionError dikembalikan ketika perubahan status goal tidak diizinkan.
+type GoalTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *GoalTransitionError) Error() string {
+	return fmt.Sprintf("goal tidak bisa diubah dari %s ke %s", e.From, e.To)
+}
+
+// goalTransitions adalah status tujuan yang diizinkan dari setiap status goal.
+// Goal yang selesai, ditinggalkan, atau diarsipkan bisa dipulihkan ke active/paused.
+var goalTransitions = map[string][]string{
+	repository.GoalStatusActive:    {repository.GoalStatusPaused, repository.GoalStatusCompleted, repository.GoalStatusAbandoned, repository.GoalStatusArchived},
+	repository.GoalStatusPaused:    {repository.GoalStatusActive, repository.GoalStatusCompleted, repository.GoalStatusAbandoned, repository.GoalStatusArchived},
+	repository.GoalStatusCompleted: {repository.GoalStatusActive, repository.GoalStatusArchived},
+	repository.GoalStatusAbandoned: {repository.GoalStatusActive, repository.GoalStatusPaused, repository.GoalStatusArchived},
+	repository.GoalStatusArchived:  {repository.GoalStatusActive, repository.GoalStatusPaused},
+}
+
+func canTransitionGoal(from, to string) bool {
+	return canTransition(goalTransitions, from, to)
+}
+
+// ChangeGoalStatus memindahkan goal ke status baru sesuai goalTransitions dan mencatatnya
+// di riwayat. Mengaktifkan kembali goal tunduk pada batas goal aktif.
+func (s *GoalService) ChangeGoalStatus(ctx context.Context, userID, goalID, status, note string) (*repository.Goal, error) {
+	if _, ok := goalTransitions[status]; !ok {
+		return nil, ErrInvalidGoalStatus
+	}
+	goal, err := s.goalRepo.GetGoalByID(ctx, userID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	if goal.Status == status {
+		return goal, nil
+	}
+	if !canTransitionGoal(goal.Status, status) {
+		return nil, &GoalTransitionError{From: goal.Status, To: status}
+	}
+
+	err = s.inTx(ctx, func(goals *repository.GoalRepository, _ *repository.RoadmapRepository) error {
+		if status == repository.GoalStatusActive {
+			if err := s.lockActiveLimit(ctx, goals, userID); err != nil {
+				return err
+			}
+		}
+		return goals.UpdateGoalStatus(ctx, userID, goalID, goal.Status, status, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.goalRepo.GetGoalByID(ctx, userID, goalID)
+}
+
+// SetGoalActive adalah pintasan untuk active <-> paused. Goal yang di-pause tidak mendapat tugas harian.
+func (s *GoalService) SetGoalActive(ctx context.Context, userID, goalID string, active bool) (*repository.Goal, error) {
+	if active {
+		return s.ChangeGoalStatus(ctx, userID, goalID, repository.GoalStatusActive, "")
+	}
+	return s.ChangeGoalStatus(ctx, userID, goalID, repository.GoalStatusPaused, "")
+}
+
+// GetGoalHistory mengembalikan riwayat status goal milik user.
+func (s *GoalService) GetGoalHistory(ctx context.Context, userID, goalID string) ([]repository.GoalStatusChange, error) {
+	if _, err := s.goalRepo.GetGoalByID(ctx, userID, goalID); err != nil {
+		return nil, err
+	}
+	return s.goalRepo.GetGoalStatusHistory(ctx, goalID)
+}
+
+// completeGoalIfDone menandai goal active/paused sebagai completed ketika semua langkah
+// roadmap-nya sudah completed.
+func (s *GoalService) completeGoalIfDone(ctx context.Context, userID, goalID string) error {
+	goal, err := s.goalRepo.GetGoalByID(ctx, userID, goalID)
+	if err != nil {
+		return err
+	}
+	if !canTransitionGoal(goal.Status, repository.GoalStatusCompleted) {
+		return nil
+	}
+	done, err := s.roadmapRepo.AllStepsCompleted(ctx, goalID)
+	if err != nil || !done {
+		return err
+	}
+
+	log.Printf("Semua langkah roadmap goal %s selesai, goal ditandai completed", goalID)
+	return s.goalRepo.UpdateGoalStatus(ctx, userID, goalID, goal.Status, repository.GoalStatusCompleted, "Semua langkah roadmap selesai")
+}
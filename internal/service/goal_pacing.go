@@ -0,0 +1,161 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+// Status pacing goal terhadap target tanggalnya.
+const (
+	PacingOnTrack = "on_track"
+	PacingBehind  = "behind"
+	PacingAhead   = "ahead"
+)
+
+// GoalPacing membandingkan progres langkah roadmap dengan waktu yang sudah berjalan
+// menuju target tanggal goal.
+type GoalPacing struct {
+	Status          string    `json:"status"`
+	TargetDate      time.Time `json:"target_date"`
+	DaysRemaining   int       `json:"days_remaining"`  // Negatif jika target sudah lewat
+	CompletedSteps  int       `json:"completed_steps"` // Termasuk langkah yang skipped
+	TotalSteps      int       `json:"total_steps"`
+	ExpectedSteps   float64   `json:"expected_steps"`   // Langkah yang seharusnya selesai jika progres linear
+	ProgressPercent float64   `json:"progress_percent"` // Persentase langkah yang sudah completed
+	ElapsedPercent  float64   `json:"elapsed_percent"`  // Persentase waktu yang sudah berjalan
+	OverdueSteps    int       `json:"overdue_steps"`    // Langkah belum selesai yang due_date-nya sudah lewat
+}
+
+// computePacing menghitung pacing goal pada waktu now. Mengembalikan nil jika goal tidak
+// punya target tanggal. tolerance adalah selisih (0-1) antara progres dan waktu berjalan
+// yang masih dianggap on_track. Hanya langkah utama yang dihitung; sub-langkah diabaikan.
+func computePacing(goal *repository.Goal, steps []repository.RoadmapStep, now time.Time, tolerance float64) *GoalPacing {
+	if goal.TargetDate == nil {
+		return nil
+	}
+	today := truncateToDate(now)
+	start := truncateToDate(goal.CreatedAt)
+	target := truncateToDate(*goal.TargetDate)
+
+	pacing := &GoalPacing{
+		TargetDate:    target,
+		DaysRemaining: daysBetween(today, target),
+	}
+	for _, step := range steps {
+		if step.ParentID != nil {
+			continue
+		}
+		pacing.TotalSteps++
+		if stepResolved(step.Status) {
+			pacing.CompletedSteps++
+		} else if step.DueDate != nil && truncateToDate(*step.DueDate).Before(today) {
+			pacing.OverdueSteps++
+		}
+	}
+
+	elapsed := 1.0
+	if total := daysBetween(start, target); total > 0 {
+		elapsed = math.Min(math.Max(float64(daysBetween(start, today))/float64(total), 0), 1)
+	}
+	progress := 1.0
+	if pacing.TotalSteps > 0 {
+		progress = float64(pacing.CompletedSteps) / float64(pacing.TotalSteps)
+	}
+	pacing.ExpectedSteps = roundTo(elapsed*float64(pacing.TotalSteps), 1)
+	pacing.ProgressPercent = roundTo(progress*100, 1)
+	pacing.ElapsedPercent = roundTo(elapsed*100, 1)
+
+	switch {
+	case progress < 1 && today.After(target):
+		pacing.Status = PacingBehind
+	case progress-elapsed > tolerance:
+		pacing.Status = PacingAhead
+	case elapsed-progress > tolerance:
+		pacing.Status = PacingBehind
+	default:
+		pacing.Status = PacingOnTrack
+	}
+	return pacing
+}
+
+// scheduleSteps mengisi due_date langkah utama yang belum tuntas, mulai dari start.
+// Tanpa target tanggal, due_date adalah akumulasi estimated_days (berhenti di langkah
+// pertama yang tidak punya estimasi). Dengan target tanggal, estimasi diskalakan agar
+// langkah terakhir jatuh tepat di target; langkah tanpa estimasi memakai rata-rata estimasi lain.
+func scheduleSteps(steps []repository.RoadmapStep, start time.Time, targetDate *time.Time) {
+	start = truncateToDate(start)
+
+	var pending []int
+	for i := range steps {
+		if steps[i].ParentID == nil && !stepResolved(steps[i].Status) {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	if targetDate == nil {
+		offset := 0
+		for _, i := range pending {
+			if steps[i].EstimatedDays == nil {
+				for _, j := range pending {
+					if j >= i {
+						steps[j].DueDate = nil
+					}
+				}
+				return
+			}
+			offset += *steps[i].EstimatedDays
+			due := start.AddDate(0, 0, offset)
+			steps[i].DueDate = &due
+		}
+		return
+	}
+
+	// Rata-rata estimasi yang ada menjadi bobot untuk langkah tanpa estimasi
+	known, knownSum := 0, 0
+	for _, i := range pending {
+		if days := steps[i].EstimatedDays; days != nil {
+			known++
+			knownSum += *days
+		}
+	}
+	fill := 1.0
+	if known > 0 {
+		fill = float64(knownSum) / float64(known)
+	}
+	estimates := make([]float64, len(pending))
+	total := 0.0
+	for k, i := range pending {
+		estimates[k] = fill
+		if days := steps[i].EstimatedDays; days != nil {
+			estimates[k] = float64(*days)
+		}
+		total += estimates[k]
+	}
+
+	window := max(daysBetween(start, *targetDate), len(pending))
+	cumulative, prev := 0.0, 0
+	for k, i := range pending {
+		cumulative += estimates[k]
+		offset := int(math.Round(cumulative / total * float64(window)))
+		// Setiap langkah mendapat minimal satu hari dan urutan due_date tetap naik
+		offset = max(offset, prev+1)
+		prev = offset
+		due := start.AddDate(0, 0, offset)
+		steps[i].DueDate = &due
+	}
+}
+
+// daysBetween menghitung selisih hari kalender dari a ke b (UTC).
+func daysBetween(a, b time.Time) int {
+	return int(truncateToDate(b).Sub(truncateToDate(a)).Hours() / 24)
+}
+
+func roundTo(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+// stepSpec adalah ringkasan satu langkah roadmap untuk tabel tes. days 0 berarti tanpa
+// estimasi; due kosong berarti tanpa due_date.
+type stepSpec struct {
+	status string
+	days   int
+	due    string
+	child  bool
+}
+
+func buildSteps(specs []stepSpec) []repository.RoadmapStep {
+	parentID := "parent"
+	steps := make([]repository.RoadmapStep, len(specs))
+	for i, spec := range specs {
+		steps[i] = repository.RoadmapStep{Order: i + 1, Status: spec.status}
+		if spec.days > 0 {
+			days := spec.days
+			steps[i].EstimatedDays = &days
+		}
+		if spec.due != "" {
+			steps[i].DueDate = mustDatePtr(spec.due)
+		}
+		if spec.child {
+			steps[i].ParentID = &parentID
+		}
+	}
+	return steps
+}
+
+const (
+	stepPending   = repository.StepStatusPending
+	stepCompleted = repository.StepStatusCompleted
+	stepSkipped   = repository.StepStatusSkipped
+)
+
+func TestComputePacing(t *testing.T) {
+	tests := []struct {
+		name    string
+		created string
+		target  string // Kosong berarti goal tanpa target tanggal
+		now     string
+		steps   []stepSpec
+		want    *GoalPacing
+	}{
+		{
+			name:    "no target date",
+			created: "2026-03-01",
+			now:     "2026-03-05",
+			steps:   []stepSpec{{status: stepPending}},
+		},
+		{
+			name:    "on track halfway",
+			created: "2026-03-01",
+			target:  "2026-03-11",
+			now:     "2026-03-06",
+			steps:   []stepSpec{{status: stepCompleted}, {status: stepPending}},
+			want: &GoalPacing{Status: PacingOnTrack, DaysRemaining: 5, CompletedSteps: 1, TotalSteps: 2,
+				ExpectedSteps: 1, ProgressPercent: 50, ElapsedPercent: 50},
+		},
+		{
+			name:    "rounding to one decimal",
+			created: "2026-03-01",
+			target:  "2026-03-04",
+			now:     "2026-03-02",
+			steps:   []stepSpec{{status: stepPending}, {status: stepPending}, {status: stepSkipped}},
+			want: &GoalPacing{Status: PacingOnTrack, DaysRemaining: 2, CompletedSteps: 1, TotalSteps: 3,
+				ExpectedSteps: 1, ProgressPercent: 33.3, ElapsedPercent: 33.3},
+		},
+		{
+			name:    "behind beyond the tolerance",
+			created: "2026-03-01",
+			target:  "2026-03-04",
+			now:     "2026-03-02",
+			steps:   []stepSpec{{status: stepPending}, {status: stepPending}},
+			want: &GoalPacing{Status: PacingBehind, DaysRemaining: 2, TotalSteps: 2,
+				ExpectedSteps: 0.7, ElapsedPercent: 33.3},
+		},
+		{
+			name:    "ahead of schedule",
+			created: "2026-03-01",
+			target:  "2026-03-11",
+			now:     "2026-03-03",
+			steps:   []stepSpec{{status: stepCompleted}, {status: stepCompleted}, {status: stepPending}, {status: stepPending}},
+			want: &GoalPacing{Status: PacingAhead, DaysRemaining: 8, CompletedSteps: 2, TotalSteps: 4,
+				ExpectedSteps: 0.8, ProgressPercent: 50, ElapsedPercent: 20},
+		},
+		{
+			name:    "target in the past with steps left",
+			created: "2026-03-01",
+			target:  "2026-03-10",
+			now:     "2026-03-12",
+			steps:   []stepSpec{{status: stepCompleted}, {status: stepPending, due: "2026-03-10"}, {status: stepPending, due: "2026-03-12"}},
+			want: &GoalPacing{Status: PacingBehind, DaysRemaining: -2, CompletedSteps: 1, TotalSteps: 3,
+				ExpectedSteps: 3, ProgressPercent: 33.3, ElapsedPercent: 100, OverdueSteps: 1},
+		},
+		{
+			name:    "all steps done after the target",
+			created: "2026-03-01",
+			target:  "2026-03-10",
+			now:     "2026-03-12",
+			steps:   []stepSpec{{status: stepCompleted}, {status: stepSkipped}},
+			want: &GoalPacing{Status: PacingOnTrack, DaysRemaining: -2, CompletedSteps: 2, TotalSteps: 2,
+				ExpectedSteps: 2, ProgressPercent: 100, ElapsedPercent: 100},
+		},
+		{
+			name:    "target set to the creation day",
+			created: "2026-03-01",
+			target:  "2026-03-01",
+			now:     "2026-03-01",
+			steps:   []stepSpec{{status: stepPending}},
+			want: &GoalPacing{Status: PacingBehind, TotalSteps: 1,
+				ExpectedSteps: 1, ElapsedPercent: 100},
+		},
+		{
+			name:    "sub-steps are ignored",
+			created: "2026-03-01",
+			target:  "2026-03-11",
+			now:     "2026-03-06",
+			steps:   []stepSpec{{status: stepCompleted}, {status: stepPending, child: true, due: "2026-03-02"}, {status: stepPending}},
+			want: &GoalPacing{Status: PacingOnTrack, DaysRemaining: 5, CompletedSteps: 1, TotalSteps: 2,
+				ExpectedSteps: 1, ProgressPercent: 50, ElapsedPercent: 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goal := &repository.Goal{CreatedAt: mustDate(tt.created)}
+			if tt.target != "" {
+				goal.TargetDate = mustDatePtr(tt.target)
+			}
+
+			got := computePacing(goal, buildSteps(tt.steps), mustDate(tt.now), 0.1)
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("pacing = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("pacing = nil")
+			}
+			if !got.TargetDate.Equal(*goal.TargetDate) {
+				t.Errorf("target date = %v, want %v", got.TargetDate, *goal.TargetDate)
+			}
+			got.TargetDate = time.Time{}
+			if *got != *tt.want {
+				t.Errorf("pacing = %+v\nwant     %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string // Kosong berarti goal tanpa target tanggal
+		steps   []stepSpec
+		wantDue []string
+	}{
+		{
+			name:    "no target accumulates estimates",
+			steps:   []stepSpec{{status: stepPending, days: 2}, {status: stepPending, days: 3}},
+			wantDue: []string{"2026-03-03", "2026-03-06"},
+		},
+		{
+			name:    "no target stops at the first step without an estimate",
+			steps:   []stepSpec{{status: stepPending, days: 2}, {status: stepPending, due: "2026-04-01"}, {status: stepPending, days: 4, due: "2026-04-05"}},
+			wantDue: []string{"2026-03-03", "", ""},
+		},
+		{
+			name:    "resolved steps and sub-steps keep their due dates",
+			steps:   []stepSpec{{status: stepCompleted, days: 5, due: "2026-02-20"}, {status: stepPending, days: 2, child: true}, {status: stepPending, days: 2}},
+			wantDue: []string{"2026-02-20", "", "2026-03-03"},
+		},
+		{
+			name:    "all steps done changes nothing",
+			target:  "2026-03-11",
+			steps:   []stepSpec{{status: stepCompleted, due: "2026-02-20"}, {status: stepSkipped}},
+			wantDue: []string{"2026-02-20", ""},
+		},
+		{
+			name:    "estimates are scaled to the target",
+			target:  "2026-03-11",
+			steps:   []stepSpec{{status: stepPending, days: 1}, {status: stepPending, days: 1}, {status: stepPending, days: 2}, {status: stepPending, days: 1}},
+			wantDue: []string{"2026-03-03", "2026-03-05", "2026-03-09", "2026-03-11"},
+		},
+		{
+			name:    "scaled offsets are rounded",
+			target:  "2026-03-11",
+			steps:   []stepSpec{{status: stepPending, days: 1}, {status: stepPending, days: 1}, {status: stepPending, days: 1}},
+			wantDue: []string{"2026-03-04", "2026-03-08", "2026-03-11"},
+		},
+		{
+			name:    "missing estimate uses the average",
+			target:  "2026-03-10",
+			steps:   []stepSpec{{status: stepPending, days: 2}, {status: stepPending}, {status: stepPending, days: 4}},
+			wantDue: []string{"2026-03-03", "2026-03-06", "2026-03-10"},
+		},
+		{
+			name:    "target too close still gives each step a day",
+			target:  "2026-03-02",
+			steps:   []stepSpec{{status: stepPending, days: 5}, {status: stepPending, days: 5}, {status: stepPending, days: 5}},
+			wantDue: []string{"2026-03-02", "2026-03-03", "2026-03-04"},
+		},
+		{
+			name:    "target in the past",
+			target:  "2026-02-25",
+			steps:   []stepSpec{{status: stepPending}, {status: stepPending}},
+			wantDue: []string{"2026-03-02", "2026-03-03"},
+		},
+		{
+			name:    "a large first estimate keeps later due dates increasing",
+			target:  "2026-03-04",
+			steps:   []stepSpec{{status: stepPending, days: 30}, {status: stepPending, days: 1}, {status: stepPending, days: 1}},
+			wantDue: []string{"2026-03-04", "2026-03-05", "2026-03-06"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := buildSteps(tt.steps)
+			var target *time.Time
+			if tt.target != "" {
+				target = mustDatePtr(tt.target)
+			}
+
+			scheduleSteps(steps, mustDate("2026-03-01").Add(15*time.Hour), target)
+
+			got := make([]string, len(steps))
+			for i, step := range steps {
+				if step.DueDate != nil {
+					got[i] = step.DueDate.Format("2006-01-02")
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantDue) {
+				t.Errorf("due dates = %q, want %q", got, tt.wantDue)
+			}
+		})
+	}
+}
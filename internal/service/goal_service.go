@@ -3,54 +3,109 @@ package service
 import (
 	"context"
 	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrActiveGoalLimit   = errors.New("jumlah goal aktif sudah mencapai batas")
+	ErrInvalidPriority   = errors.New("priority goal harus di antara 1 dan 10")
+	ErrInvalidTargetDate = errors.New("target tanggal goal harus setelah hari ini")
+	ErrInvalidParentStep = errors.New("parent harus langkah utama dari goal yang sama")
+	ErrDescriptionEmpty  = errors.New("deskripsi goal tidak boleh kosong")
+)
+
 type GoalService struct {
-	db *pgxpool.Pool
-	goalRepo    *repository.GoalRepository
-	roadmapRepo *repository.RoadmapRepository
-	aiService   *AIService // <-- 1. Tambahkan dependensi ke AI Service
+	db              *pgxpool.Pool
+	goalRepo        *repository.GoalRepository
+	roadmapRepo     *repository.RoadmapRepository
+	aiService       *AIService // <-- 1. Tambahkan dependensi ke AI Service
+	quota           *QuotaService
+	maxActive       int     // Batas goal aktif per user (0 = tidak dibatasi)
+	pacingTolerance float64 // Selisih progres vs waktu berjalan yang masih dianggap on_track
 }
 
 // 2. Terima AIService sebagai argumen
-func NewGoalService(db *pgxpool.Pool, goalRepo *repository.GoalRepository, roadmapRepo *repository.RoadmapRepository, aiService *AIService) *GoalService {
-    return &GoalService{
-        db:          db,
-        goalRepo:    goalRepo,
-        roadmapRepo: roadmapRepo,
-        aiService:   aiService,
-    }
+func NewGoalService(db *pgxpool.Pool, goalRepo *repository.GoalRepository, roadmapRepo *repository.RoadmapRepository, aiService *AIService, quota *QuotaService) *GoalService {
+	tolerance := 0.1
+	if v, err := strconv.ParseFloat(config.Get("GOAL_PACING_TOLERANCE"), 64); err == nil && v >= 0 && v < 1 {
+		tolerance = v
+	}
+	return &GoalService{
+		db:              db,
+		goalRepo:        goalRepo,
+		roadmapRepo:     roadmapRepo,
+		aiService:       aiService,
+		quota:           quota,
+		maxActive:       intFromConfig("MAX_ACTIVE_GOALS", 5),
+		pacingTolerance: tolerance,
+	}
 }
 
 // Fungsi callAIToGenerateRoadmap yang lama bisa dihapus.
 
-func (s *GoalService) CreateNewGoal(ctx context.Context, userID string, goalDescription string) (*repository.Goal, []repository.RoadmapStep, error) {
-	// 4. Panggil AI service yang asli, bukan mock lagi
-	steps, err := s.aiService.GenerateRoadmapWithAI(ctx, goalDescription)
-	if err != nil {
+// CreateNewGoal membuat goal aktif baru beserta roadmap-nya. priority 0 berarti bobot default;
+// targetDate opsional dan dipakai untuk menjadwalkan due_date setiap langkah.
+func (s *GoalService) CreateNewGoal(ctx context.Context, userID string, goalDescription string, priority int, targetDate *time.Time) (*repository.Goal, []repository.RoadmapStep, error) {
+	if strings.TrimSpace(goalDescription) == "" {
+		return nil, nil, ErrDescriptionEmpty
+	}
+	if priority == 0 {
+		priority = repository.MinGoalPriority
+	}
+	if !validPriority(priority) {
+		return nil, nil, ErrInvalidPriority
+	}
+	if !validTargetDate(targetDate) {
+		return nil, nil, ErrInvalidTargetDate
+	}
+	// Diperiksa sebelum AI dipanggil agar tidak membuang kuota, lalu diperiksa lagi saat menyimpan
+	if err := s.checkActiveLimit(ctx, s.goalRepo, userID); err != nil {
 		return nil, nil, err
 	}
 
-	newGoal := &repository.Goal{
-		UserID:      userID,
-		Description: goalDescription,
-		IsActive:    true,
-	}
-	goalID, err := s.goalRepo.CreateGoal(ctx, newGoal)
+	// 4. Panggil AI service (dengan template cadangan jika AI gagal). Kuota generasi hanya
+	//    terpakai jika provider AI benar-benar dipanggil
+	aiCtx := s.quota.WithGenerationCharge(withAIScope(ctx, userID, ""), userID)
+	steps, source, err := s.aiService.RoadmapWithFallback(aiCtx, goalDescription, targetDate)
 	if err != nil {
 		return nil, nil, err
 	}
-	newGoal.ID = goalID
 
-	for i := range steps {
-		steps[i].GoalID = goalID
+	newGoal := &repository.Goal{
+		UserID:        userID,
+		Description:   goalDescription,
+		Status:        repository.GoalStatusActive,
+		RoadmapSource: source,
+		Priority:      priority,
+		TargetDate:    targetDate,
 	}
 
-	err = s.roadmapRepo.CreateRoadmapSteps(ctx, steps)
+	// Goal dan roadmap-nya disimpan bersama: jika salah satu gagal, tidak ada goal tanpa roadmap
+	err = s.inTx(ctx, func(goals *repository.GoalRepository, roadmap *repository.RoadmapRepository) error {
+		if err := s.lockActiveLimit(ctx, goals, userID); err != nil {
+			return err
+		}
+		goalID, err := goals.CreateGoal(ctx, newGoal)
+		if err != nil {
+			return err
+		}
+		newGoal.ID = goalID
+
+		for i := range steps {
+			steps[i].GoalID = goalID
+			steps[i].Status = "pending"
+		}
+		scheduleSteps(steps, newGoal.CreatedAt, targetDate)
+		return roadmap.CreateRoadmapSteps(ctx, steps)
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -58,6 +113,7 @@ func (s *GoalService) CreateNewGoal(ctx context.Context, userID string, goalDesc
 	return newGoal, steps, nil
 }
 
+// GetActiveGoal mengembalikan goal aktif utama (bobot tertinggi) beserta roadmap-nya.
 func (s *GoalService) GetActiveGoal(ctx context.Context, userID string) (*repository.Goal, []repository.RoadmapStep, error) {
 	// 1. Dapatkan goal yang aktif
 	goal, err := s.goalRepo.GetActiveGoalByUserID(ctx, userID)
@@ -69,43 +125,57 @@ func (s *GoalService) GetActiveGoal(ctx context.Context, userID string) (*reposi
 		return nil, nil, err // Error lain yang tidak terduga
 	}
 
-	// 2. Jika goal ditemukan, dapatkan semua roadmap steps-nya
+	// 2. Jika goal ditemukan, dapatkan semua roadmap steps-nya dalam bentuk pohon
 	steps, err := s.roadmapRepo.GetRoadmapStepsByGoalID(ctx, goal.ID)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return goal, steps, nil
+	return goal, buildStepTree(steps), nil
 }
 
 // UpdateGoal mengorkestrasi proses update tujuan dan regenerasi roadmap.
 func (s *GoalService) UpdateGoal(ctx context.Context, userID, goalID, newDescription string) (*repository.Goal, []repository.RoadmapStep, error) {
-    // 1. Update deskripsi goal di database
-    if err := s.goalRepo.UpdateGoalDescription(ctx, userID, goalID, newDescription); err != nil {
-        return nil, nil, err
+    if strings.TrimSpace(newDescription) == "" {
+        return nil, nil, ErrDescriptionEmpty
     }
-
-    // 2. Hapus semua roadmap steps yang lama
-    if err := s.roadmapRepo.DeleteRoadmapStepsByGoalID(ctx, goalID); err != nil {
+    goal, err := s.goalRepo.GetGoalByID(ctx, userID, goalID)
+    if err != nil {
         return nil, nil, err
     }
 
-    // 3. Panggil AI untuk membuat roadmap steps yang baru
-    newSteps, err := s.aiService.GenerateRoadmapWithAI(ctx, newDescription)
+    // 1. Panggil AI lebih dulu (dengan template cadangan), di luar transaksi agar koneksi
+    //    database tidak tertahan selama menunggu AI dan roadmap lama tetap utuh jika AI gagal
+    aiCtx := s.quota.WithGenerationCharge(withAIScope(ctx, userID, goalID), userID)
+    newSteps, source, err := s.aiService.RoadmapWithFallback(aiCtx, newDescription, goal.TargetDate)
     if err != nil {
         return nil, nil, err
     }
-
-    // 4. Hubungkan dan simpan roadmap steps yang baru
     for i := range newSteps {
         newSteps[i].GoalID = goalID
+        newSteps[i].Status = "pending"
     }
-    if err := s.roadmapRepo.CreateRoadmapSteps(ctx, newSteps); err != nil {
+    scheduleSteps(newSteps, time.Now(), goal.TargetDate)
+
+    // 2-4. Perbarui deskripsi, ganti roadmap lama, dan catat asalnya dalam satu transaksi
+    err = s.inTx(ctx, func(goals *repository.GoalRepository, roadmap *repository.RoadmapRepository) error {
+        if err := goals.UpdateGoalDescription(ctx, userID, goalID, newDescription); err != nil {
+            return err
+        }
+        if err := roadmap.DeleteRoadmapStepsByGoalID(ctx, goalID); err != nil {
+            return err
+        }
+        if err := roadmap.CreateRoadmapSteps(ctx, newSteps); err != nil {
+            return err
+        }
+        return goals.UpdateRoadmapSource(ctx, goalID, source)
+    })
+    if err != nil {
         return nil, nil, err
     }
 
     // 5. Ambil data goal yang sudah terupdate untuk dikembalikan
-    updatedGoal, err := s.goalRepo.GetActiveGoalByUserID(ctx, userID)
+    updatedGoal, err := s.goalRepo.GetGoalByID(ctx, userID, goalID)
     if err != nil {
         return nil, nil, err
     }
@@ -113,19 +183,34 @@ func (s *GoalService) UpdateGoal(ctx context.Context, userID, goalID, newDescrip
     return updatedGoal, newSteps, nil
 }
 
-func (s *GoalService) AddRoadmapStep(ctx context.Context, goalID, title string) (*repository.RoadmapStep, error) {
-    // 1. Dapatkan urutan terakhir
-    lastOrder, err := s.roadmapRepo.GetLastStepOrder(ctx, goalID)
+// AddRoadmapStep menambahkan langkah di akhir roadmap, atau sebagai sub-langkah terakhir
+// jika parentID diisi. Sub-langkah hanya boleh satu tingkat.
+func (s *GoalService) AddRoadmapStep(ctx context.Context, userID, goalID, title string, parentID *string, isMilestone bool) (*repository.RoadmapStep, error) {
+    // Pastikan goal milik user yang sedang login
+    if _, err := s.goalRepo.GetGoalByID(ctx, userID, goalID); err != nil {
+        return nil, err
+    }
+    if parentID != nil {
+        parent, err := s.roadmapRepo.GetStepByID(ctx, *parentID)
+        if err != nil || parent.GoalID != goalID || parent.ParentID != nil {
+            return nil, ErrInvalidParentStep
+        }
+    }
+
+    // 1. Dapatkan urutan terakhir di antara saudara satu parent
+    lastOrder, err := s.roadmapRepo.GetLastStepOrder(ctx, goalID, parentID)
     if err != nil {
         return nil, err
     }
 
     // 2. Buat objek step baru dengan urutan + 1
     newStep := &repository.RoadmapStep{
-        GoalID:  goalID,
-        Order:   lastOrder + 1,
-        Title:   title,
-        Status:  "pending",
+        GoalID:      goalID,
+        Order:       lastOrder + 1,
+        Title:       title,
+        Status:      "pending",
+        ParentID:    parentID,
+        IsMilestone: isMilestone,
     }
 
     // 3. Simpan ke database
@@ -137,43 +222,244 @@ func (s *GoalService) UpdateRoadmapStep(ctx context.Context, userID, stepID, new
 }
 
 func (s *GoalService) DeleteRoadmapStep(ctx context.Context, userID, stepID string) error {
-    // Kita butuh transaksi karena ada beberapa operasi database
-    tx, err := s.db.Begin(ctx)
-    if err != nil {
-        return err
-    }
-    defer tx.Rollback(ctx)
-
     // 1. Dapatkan detail step yang mau dihapus untuk tahu order & goalId-nya
     stepToDelete, err := s.roadmapRepo.GetStepByID(ctx, stepID)
     if err != nil {
         return errors.New("step not found")
     }
 
-    // 2. Validasi kepemilikan
-    activeGoal, err := s.goalRepo.GetActiveGoalByUserID(ctx, userID)
-    if err != nil || activeGoal.ID != stepToDelete.GoalID {
+    // 2. Validasi kepemilikan (goal tidak harus yang aktif)
+    if _, err := s.goalRepo.GetGoalByID(ctx, userID, stepToDelete.GoalID); err != nil {
         return errors.New("user does not have permission to delete this step")
     }
 
-    // 3. Hapus step di dalam transaksi
-    if err := s.roadmapRepo.DeleteRoadmapStep(ctx, tx, stepID); err != nil {
+    // 3-4. Hapus step lalu perbarui urutan step lain di dalam transaksi yang sama
+    err = s.inTx(ctx, func(_ *repository.GoalRepository, roadmap *repository.RoadmapRepository) error {
+        if err := roadmap.DeleteRoadmapStep(ctx, stepID); err != nil {
+            return err
+        }
+        return roadmap.RenumberStepsAfterDelete(ctx, stepToDelete.GoalID, stepToDelete.ParentID, stepToDelete.Order)
+    })
+    if err != nil {
         return err
     }
 
-    // 4. Perbarui urutan step lain di dalam transaksi yang sama
-    if err := s.roadmapRepo.RenumberStepsAfterDelete(ctx, tx, stepToDelete.GoalID, stepToDelete.Order); err != nil {
-        return err
+    // Menghapus langkah terakhir yang belum selesai juga bisa menuntaskan parent atau goal.
+    // Penghapusan sudah di-commit, jadi kegagalan di sini hanya di-log; parent atau goal
+    // bisa diselesaikan manual lewat statusnya.
+    if stepToDelete.ParentID != nil {
+        if err := s.completeParentIfDone(ctx, userID, *stepToDelete.ParentID); err != nil {
+            log.Printf("ERROR auto-completing parent step %s after deleting step %s: %v", *stepToDelete.ParentID, stepID, err)
+        }
+        return nil
     }
-
-    // 5. Jika semua berhasil, commit transaksinya
-    return tx.Commit(ctx)
+    if err := s.completeGoalIfDone(ctx, userID, stepToDelete.GoalID); err != nil {
+        log.Printf("ERROR auto-completing goal %s after deleting step %s: %v", stepToDelete.GoalID, stepID, err)
+    }
+    return nil
 }
 func (s *GoalService) ReorderRoadmapSteps(ctx context.Context, userID string, stepIDs []string) error {
 	return s.roadmapRepo.ReorderRoadmapSteps(ctx, userID, stepIDs)
 }
 
+// UpdateRoadmapStepStatus memindahkan langkah roadmap ke status baru sesuai stepTransitions.
+// Langkah yang tuntas (completed/skipped) bisa ikut menuntaskan parent dan goal-nya; sub-langkah
+// yang dibuka kembali ikut membuka parent-nya.
 func (s *GoalService) UpdateRoadmapStepStatus(ctx context.Context, userID, stepID, status string) error {
-	// Untuk saat ini, service hanya meneruskan panggilan ke repository yang sudah aman
-	return s.roadmapRepo.UpdateStepStatus(ctx, userID, stepID, status)
-}
\ No newline at end of file
+	if _, ok := stepTransitions[status]; !ok {
+		return ErrInvalidStepStatus
+	}
+	step, err := s.roadmapRepo.GetStepByID(ctx, stepID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.goalRepo.GetGoalByID(ctx, userID, step.GoalID); err != nil {
+		return err
+	}
+	if step.Status == status {
+		return nil
+	}
+	if !canTransition(stepTransitions, step.Status, status) {
+		return &StatusTransitionError{Entity: "roadmap_step", From: step.Status, To: status}
+	}
+
+	if err := s.roadmapRepo.UpdateStepStatus(ctx, userID, stepID, step.Status, status); err != nil {
+		return err
+	}
+
+	// Status langkah sudah tersimpan; gagal menyelaraskan parent atau goal hanya di-log agar
+	// klien tetap menerima langkah yang baru; keduanya bisa diubah manual lewat statusnya.
+	if step.ParentID != nil {
+		if stepResolved(status) {
+			err = s.completeParentIfDone(ctx, userID, *step.ParentID)
+		} else {
+			err = s.reopenParent(ctx, userID, *step.ParentID, status)
+		}
+		if err != nil {
+			log.Printf("ERROR syncing parent step %s after step %s: %v", *step.ParentID, stepID, err)
+		}
+		return nil
+	}
+	if !stepResolved(status) {
+		return nil
+	}
+	if err := s.completeGoalIfDone(ctx, userID, step.GoalID); err != nil {
+		log.Printf("ERROR auto-completing goal %s after step %s: %v", step.GoalID, stepID, err)
+	}
+	return nil
+}
+
+// completeParentIfDone menandai langkah utama completed ketika semua sub-langkahnya tuntas.
+func (s *GoalService) completeParentIfDone(ctx context.Context, userID, parentID string) error {
+	done, err := s.roadmapRepo.AllChildrenResolved(ctx, parentID)
+	if err != nil || !done {
+		return err
+	}
+	parent, err := s.roadmapRepo.GetStepByID(ctx, parentID)
+	if err != nil || stepResolved(parent.Status) {
+		return err
+	}
+	return s.UpdateRoadmapStepStatus(ctx, userID, parentID, repository.StepStatusCompleted)
+}
+
+// reopenParent menjaga status langkah utama selaras dengan sub-langkah yang kembali aktif.
+func (s *GoalService) reopenParent(ctx context.Context, userID, parentID, childStatus string) error {
+	parent, err := s.roadmapRepo.GetStepByID(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if stepResolved(parent.Status) {
+		return s.UpdateRoadmapStepStatus(ctx, userID, parentID, repository.StepStatusInProgress)
+	}
+	if childStatus == repository.StepStatusInProgress {
+		return s.roadmapRepo.MarkStepInProgress(ctx, parentID)
+	}
+	return nil
+}
+
+// GetStepHistory mengembalikan riwayat status langkah roadmap milik user.
+func (s *GoalService) GetStepHistory(ctx context.Context, userID, stepID string) ([]repository.StatusChange, error) {
+	step, err := s.roadmapRepo.GetStepByID(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.goalRepo.GetGoalByID(ctx, userID, step.GoalID); err != nil {
+		return nil, err
+	}
+	return s.roadmapRepo.GetStepStatusHistory(ctx, stepID)
+}
+
+// ListGoals mengembalikan goal milik user, difilter dengan statuses jika tidak kosong.
+func (s *GoalService) ListGoals(ctx context.Context, userID string, statuses []string) ([]repository.Goal, error) {
+	for _, status := range statuses {
+		if _, ok := goalTransitions[status]; !ok {
+			return nil, ErrInvalidGoalStatus
+		}
+	}
+	return s.goalRepo.ListGoalsByUserID(ctx, userID, statuses)
+}
+
+// GetGoal mengembalikan satu goal milik user beserta roadmap-nya (dalam bentuk pohon).
+func (s *GoalService) GetGoal(ctx context.Context, userID, goalID string) (*repository.Goal, []repository.RoadmapStep, error) {
+	goal, err := s.goalRepo.GetGoalByID(ctx, userID, goalID)
+	if err != nil {
+		return nil, nil, err
+	}
+	steps, err := s.roadmapRepo.GetRoadmapStepsByGoalID(ctx, goal.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return goal, buildStepTree(steps), nil
+}
+
+// UpdateGoalPriority mengubah bobot goal (1-10).
+func (s *GoalService) UpdateGoalPriority(ctx context.Context, userID, goalID string, priority int) (*repository.Goal, error) {
+	if !validPriority(priority) {
+		return nil, ErrInvalidPriority
+	}
+	if err := s.goalRepo.UpdateGoalPriority(ctx, userID, goalID, priority); err != nil {
+		return nil, err
+	}
+	return s.goalRepo.GetGoalByID(ctx, userID, goalID)
+}
+
+// UpdateGoalTargetDate mengubah (atau menghapus, jika nil) target tanggal goal lalu
+// menjadwalkan ulang due_date langkah yang belum selesai mulai hari ini, dalam satu transaksi
+// agar target dan jadwal langkah tidak pernah tersimpan sebagian.
+func (s *GoalService) UpdateGoalTargetDate(ctx context.Context, userID, goalID string, targetDate *time.Time) (*repository.Goal, []repository.RoadmapStep, error) {
+	if !validTargetDate(targetDate) {
+		return nil, nil, ErrInvalidTargetDate
+	}
+
+	var steps []repository.RoadmapStep
+	err := s.inTx(ctx, func(goals *repository.GoalRepository, roadmap *repository.RoadmapRepository) error {
+		if err := goals.UpdateGoalTargetDate(ctx, userID, goalID, targetDate); err != nil {
+			return err
+		}
+		var err error
+		if steps, err = roadmap.GetRoadmapStepsByGoalID(ctx, goalID); err != nil {
+			return err
+		}
+		scheduleSteps(steps, time.Now(), targetDate)
+		return roadmap.UpdateStepDueDates(ctx, steps)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	goal, err := s.goalRepo.GetGoalByID(ctx, userID, goalID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return goal, buildStepTree(steps), nil
+}
+
+// Pacing menghitung apakah goal on_track, behind, atau ahead terhadap target tanggalnya.
+// Mengembalikan nil jika goal tidak punya target tanggal.
+func (s *GoalService) Pacing(goal *repository.Goal, steps []repository.RoadmapStep) *GoalPacing {
+	return computePacing(goal, steps, time.Now(), s.pacingTolerance)
+}
+
+// inTx menjalankan fn dalam satu transaksi, dengan repository yang terikat ke transaksi tersebut.
+// Transaksi di-commit jika fn tidak mengembalikan error, selain itu di-rollback.
+func (s *GoalService) inTx(ctx context.Context, fn func(goals *repository.GoalRepository, roadmap *repository.RoadmapRepository) error) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		return fn(s.goalRepo.WithTx(tx), s.roadmapRepo.WithTx(tx))
+	})
+}
+
+// checkActiveLimit mengembalikan ErrActiveGoalLimit jika user sudah mencapai batas goal aktif.
+// Di dalam transaksi yang akan menambah goal aktif, panggil lockActiveLimit.
+func (s *GoalService) checkActiveLimit(ctx context.Context, goals *repository.GoalRepository, userID string) error {
+	if s.maxActive <= 0 {
+		return nil
+	}
+	count, err := goals.CountActiveGoals(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if count >= s.maxActive {
+		return ErrActiveGoalLimit
+	}
+	return nil
+}
+
+// lockActiveLimit mengunci batas goal aktif user sampai transaksi goals selesai lalu memeriksanya,
+// sehingga request paralel yang menambah goal aktif berjalan bergantian dan batas tidak terlampaui.
+func (s *GoalService) lockActiveLimit(ctx context.Context, goals *repository.GoalRepository, userID string) error {
+	if s.maxActive <= 0 {
+		return nil
+	}
+	if err := goals.LockActiveGoals(ctx, userID); err != nil {
+		return err
+	}
+	return s.checkActiveLimit(ctx, goals, userID)
+}
+
+func validTargetDate(targetDate *time.Time) bool {
+	return targetDate == nil || truncateToDate(*targetDate).After(truncateToDate(time.Now()))
+}
+
+func validPriority(priority int) bool {
+	return priority >= repository.MinGoalPriority && priority <= repository.MaxGoalPriority
+}
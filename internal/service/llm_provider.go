@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
+)
+
+// GenerationKind menandai jenis panggilan AI, dipakai provider untuk memilih perilaku.
+type GenerationKind string
+
+const (
+	KindRoadmap        GenerationKind = "roadmap"
+	KindDailyTasks     GenerationKind = "daily_tasks"
+	KindReviewFeedback GenerationKind = "review_feedback"
+	KindStepSplit      GenerationKind = "step_split"    // Memecah satu langkah menjadi langkah yang lebih kecil
+	KindStepRephrase   GenerationKind = "step_rephrase" // Merumuskan ulang judul satu langkah
+	KindRoadmapTail    GenerationKind = "roadmap_tail"  // Membuat ulang sisa roadmap yang belum tuntas
+)
+
+// LLMRequest adalah satu permintaan teks ke model bahasa.
+type LLMRequest struct {
+	Kind   GenerationKind
+	Prompt string
+}
+
+// LLMResponse adalah teks mentah yang dikembalikan model beserta pemakaian token
+// (nol jika provider tidak melaporkannya).
+type LLMResponse struct {
+	Text             string
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// LLMProvider adalah abstraksi di atas model bahasa yang dipakai AIService.
+type LLMProvider interface {
+	Name() string
+	Generate(ctx context.Context, req LLMRequest) (*LLMResponse, error)
+}
+
+// StreamingLLMProvider adalah provider yang bisa mengirim teks secara bertahap.
+// onChunk dipanggil untuk setiap potongan teks; jika onChunk mengembalikan error,
+// streaming dihentikan. Respons akhir berisi teks lengkap.
+type StreamingLLMProvider interface {
+	LLMProvider
+	GenerateStream(ctx context.Context, req LLMRequest, onChunk func(chunk string) error) (*LLMResponse, error)
+}
+
+// NewLLMProviderFromConfig memilih provider berdasarkan AI_PROVIDER (default: gemini).
+func NewLLMProviderFromConfig() LLMProvider {
+	name := strings.ToLower(strings.TrimSpace(config.Get("AI_PROVIDER")))
+	switch name {
+	case "", "gemini":
+		apiKey := config.Get("GEMINI_API_KEY")
+		if apiKey == "" {
+			log.Fatal("GEMINI_API_KEY environment variable is not set (set AI_PROVIDER=stub to run offline)")
+		}
+		modelName := config.Get("GEMINI_MODEL")
+		if modelName == "" {
+			modelName = "gemini-1.5-flash"
+		}
+		provider, err := NewGeminiProvider(context.Background(), apiKey, modelName)
+		if err != nil {
+			log.Fatalf("Failed to create genai client: %v", err)
+		}
+		return provider
+	case "openai":
+		baseURL := config.Get("OPENAI_BASE_URL")
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		modelName := config.Get("OPENAI_MODEL")
+		if modelName == "" {
+			log.Fatal("OPENAI_MODEL environment variable is not set")
+		}
+		return NewOpenAIProvider(baseURL, config.Get("OPENAI_API_KEY"), modelName, nil)
+	case "stub":
+		log.Println("AI_PROVIDER=stub: menggunakan provider AI lokal (konten statis)")
+		return NewStubProvider()
+	default:
+		log.Fatalf("Unknown AI_PROVIDER %q (supported: gemini, openai, stub)", name)
+		return nil
+	}
+}
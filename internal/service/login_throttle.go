@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LoginThrottledError dikembalikan ketika login ditahan karena terlalu banyak percobaan gagal
+// untuk akun atau IP tersebut.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool // true jika sudah mencapai batas lockout, bukan sekadar jeda
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("terlalu banyak percobaan login gagal, coba lagi dalam %s", e.RetryAfter.Round(time.Second))
+}
+
+// loginThrottle menghitung jeda login dari riwayat login gagal. Setelah sejumlah percobaan
+// gratis, setiap kegagalan berikutnya menggandakan jeda (1 detik, 2, 4, ...) sampai batas
+// lockout tercapai; setelah itu login ditolak selama durasi lockout sejak kegagalan terakhir.
+// Batas per akun dihitung dari kegagalan berturut-turut; batas per IP dari semua kegagalan
+// dalam window, agar satu IP tidak bisa mencoba banyak akun sekaligus.
+type loginThrottle struct {
+	window         time.Duration
+	baseDelay      time.Duration
+	lockout        time.Duration
+	freeAttempts   int
+	lockoutAfter   int
+	ipFreeAttempts int
+	ipLockoutAfter int
+}
+
+func newLoginThrottleFromConfig() loginThrottle {
+	return loginThrottle{
+		window:         time.Duration(intFromConfig("LOGIN_FAILURE_WINDOW_MINUTES", 60)) * time.Minute,
+		baseDelay:      time.Second,
+		lockout:        time.Duration(intFromConfig("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+		freeAttempts:   intFromConfig("LOGIN_FREE_ATTEMPTS", 3),
+		lockoutAfter:   intFromConfig("LOGIN_LOCKOUT_THRESHOLD", 10),
+		ipFreeAttempts: intFromConfig("LOGIN_IP_FREE_ATTEMPTS", 20),
+		ipLockoutAfter: intFromConfig("LOGIN_IP_LOCKOUT_THRESHOLD", 100),
+	}
+}
+
+// check mengembalikan *LoginThrottledError jika login harus ditahan, atau nil.
+func (t loginThrottle) check(stats *repository.LoginFailureStats, now time.Time) error {
+	emailWait, emailLocked := t.delay(stats.EmailFailures, stats.EmailLastFailure, t.freeAttempts, t.lockoutAfter, now)
+	ipWait, ipLocked := t.delay(stats.IPFailures, stats.IPLastFailure, t.ipFreeAttempts, t.ipLockoutAfter, now)
+	if emailWait <= 0 && ipWait <= 0 {
+		return nil
+	}
+	if ipWait > emailWait {
+		return &LoginThrottledError{RetryAfter: ipWait, Locked: ipLocked}
+	}
+	return &LoginThrottledError{RetryAfter: emailWait, Locked: emailLocked}
+}
+
+func (t loginThrottle) delay(failures int, last *time.Time, free, lockoutAfter int, now time.Time) (time.Duration, bool) {
+	if last == nil || failures < free {
+		return 0, false
+	}
+
+	wait, locked := t.lockout, true
+	if failures < lockoutAfter {
+		wait, locked = t.baseDelay, false
+		// Gandakan jeda tiap kegagalan; berhenti menggandakan sebelum melewati durasi lockout
+		for i := free; i < failures && wait < t.lockout; i++ {
+			wait *= 2
+		}
+		if wait > t.lockout {
+			wait = t.lockout
+		}
+	}
+
+	remaining := last.Add(wait).Sub(now)
+	if remaining <= 0 {
+		return 0, false
+	}
+	return remaining, locked
+}
+
+// normalizeLoginEmail menyamakan email untuk penghitungan percobaan, agar variasi huruf
+// besar/kecil tidak menghindari batas per akun.
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// reserveLoginAttempt memeriksa throttle lalu langsung mencatat percobaan sebagai gagal,
+// sebelum password diperiksa. Pemeriksaan dan pencatatan berjalan di bawah advisory lock
+// email dan IP, jadi request paralel selalu melihat percobaan satu sama lain dan tidak bisa
+// melewati backoff atau lockout. Hasil akhirnya diisi lewat finishLoginAttempt.
+func (s *AuthService) reserveLoginAttempt(ctx context.Context, attempt *repository.LoginAttempt) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		attempts := s.loginAttempts.WithTx(tx)
+		if err := attempts.LockLoginAttempts(ctx, attempt.Email, attempt.IPAddress); err != nil {
+			return err
+		}
+		now := time.Now()
+		stats, err := attempts.GetLoginFailureStats(ctx, attempt.Email, attempt.IPAddress, now.Add(-s.throttle.window))
+		if err != nil {
+			return err
+		}
+		if err := s.throttle.check(stats, now); err != nil {
+			return err
+		}
+		return attempts.CreateLoginAttempt(ctx, attempt)
+	})
+}
+
+// finishLoginAttempt menyimpan user dan hasil akhir percobaan. Gagal menyimpan hanya di-log:
+// percobaan tetap tercatat sebagai gagal, yang aman untuk throttling.
+func (s *AuthService) finishLoginAttempt(ctx context.Context, attempt *repository.LoginAttempt) {
+	if err := s.loginAttempts.FinishLoginAttempt(ctx, attempt); err != nil {
+		log.Printf("ERROR recording login attempt for %s: %v", attempt.Email, err)
+	}
+}
+
+// ListFailedLogins mengembalikan riwayat login gagal ke akun user, terbaru lebih dulu.
+func (s *AuthService) ListFailedLogins(ctx context.Context, userID string, limit, offset int) ([]repository.LoginAttempt, error) {
+	return s.loginAttempts.ListFailedLoginsByUserID(ctx, userID, limit, offset)
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// compareDummyPassword menjalankan bcrypt untuk email yang tidak terdaftar agar waktu respons
+// login tidak membocorkan apakah akun tersebut ada.
+func compareDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("momentum-dummy-password"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
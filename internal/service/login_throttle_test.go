@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+func testLoginThrottle() loginThrottle {
+	return loginThrottle{
+		window:         time.Hour,
+		baseDelay:      time.Second,
+		lockout:        15 * time.Minute,
+		freeAttempts:   3,
+		lockoutAfter:   10,
+		ipFreeAttempts: 20,
+		ipLockoutAfter: 100,
+	}
+}
+
+func TestLoginThrottleDelay(t *testing.T) {
+	throttle := testLoginThrottle()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		last := now.Add(-d)
+		return &last
+	}
+
+	tests := []struct {
+		name         string
+		failures     int
+		last         *time.Time
+		free         int
+		lockoutAfter int
+		wantWait     time.Duration
+		wantLocked   bool
+	}{
+		{name: "no failure recorded", failures: 5, free: 3, lockoutAfter: 10},
+		{name: "within free attempts", failures: 2, last: ago(0), free: 3, lockoutAfter: 10},
+		{name: "first throttled failure waits the base delay", failures: 3, last: ago(0), free: 3, lockoutAfter: 10, wantWait: time.Second},
+		{name: "delay doubles per failure", failures: 4, last: ago(0), free: 3, lockoutAfter: 10, wantWait: 2 * time.Second},
+		{name: "delay keeps doubling", failures: 6, last: ago(0), free: 3, lockoutAfter: 10, wantWait: 8 * time.Second},
+		{name: "last failure before the lockout", failures: 9, last: ago(0), free: 3, lockoutAfter: 10, wantWait: 64 * time.Second},
+		{name: "only the remaining delay is returned", failures: 4, last: ago(1500 * time.Millisecond), free: 3, lockoutAfter: 10, wantWait: 500 * time.Millisecond},
+		{name: "delay already elapsed", failures: 4, last: ago(2 * time.Second), free: 3, lockoutAfter: 10},
+		{name: "threshold reached locks out", failures: 10, last: ago(0), free: 3, lockoutAfter: 10, wantWait: 15 * time.Minute, wantLocked: true},
+		{name: "lockout counts from the last failure", failures: 12, last: ago(5 * time.Minute), free: 3, lockoutAfter: 10, wantWait: 10 * time.Minute, wantLocked: true},
+		{name: "lockout expired", failures: 12, last: ago(16 * time.Minute), free: 3, lockoutAfter: 10},
+		{name: "doubling is capped at the lockout duration", failures: 99, last: ago(0), free: 20, lockoutAfter: 100, wantWait: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, locked := throttle.delay(tt.failures, tt.last, tt.free, tt.lockoutAfter, now)
+			if wait != tt.wantWait || locked != tt.wantLocked {
+				t.Errorf("delay = %v, locked %v; want %v, locked %v", wait, locked, tt.wantWait, tt.wantLocked)
+			}
+		})
+	}
+}
+
+func TestLoginThrottleCheck(t *testing.T) {
+	throttle := testLoginThrottle()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	last := now.Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		stats      repository.LoginFailureStats
+		wantWait   time.Duration // 0 berarti login tidak ditahan
+		wantLocked bool
+	}{
+		{name: "clean history", stats: repository.LoginFailureStats{}},
+		{
+			name:  "below both free limits",
+			stats: repository.LoginFailureStats{EmailFailures: 2, EmailLastFailure: &last, IPFailures: 19, IPLastFailure: &last},
+		},
+		{
+			name:       "account locked",
+			stats:      repository.LoginFailureStats{EmailFailures: 10, EmailLastFailure: &last, IPFailures: 10, IPLastFailure: &last},
+			wantWait:   14 * time.Minute,
+			wantLocked: true,
+		},
+		{
+			name:       "ip locked across accounts",
+			stats:      repository.LoginFailureStats{EmailFailures: 1, EmailLastFailure: &last, IPFailures: 100, IPLastFailure: &last},
+			wantWait:   14 * time.Minute,
+			wantLocked: true,
+		},
+		{
+			name:     "longer wait wins",
+			stats:    repository.LoginFailureStats{EmailFailures: 9, EmailLastFailure: &last, IPFailures: 20, IPLastFailure: &last},
+			wantWait: 4 * time.Second, // Email: 64s - 60s; IP: 1s sudah lewat
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := throttle.check(&tt.stats, now)
+			if tt.wantWait == 0 {
+				if err != nil {
+					t.Fatalf("check = %v, want nil", err)
+				}
+				return
+			}
+			var throttled *LoginThrottledError
+			if !errors.As(err, &throttled) || throttled.RetryAfter != tt.wantWait || throttled.Locked != tt.wantLocked {
+				t.Fatalf("check = %#v, want RetryAfter %v Locked %v", err, tt.wantWait, tt.wantLocked)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider berbicara dengan endpoint /v1/chat/completions yang kompatibel dengan OpenAI
+// (OpenAI, llama.cpp server, vLLM, Ollama, dll).
+type OpenAIProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// chatCompletionChunk adalah satu event "data:" pada mode stream.
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta chatMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage,omitempty"`
+}
+
+// NewOpenAIProvider membuat provider baru. baseURL biasanya diakhiri dengan "/v1",
+// misalnya "http://localhost:11434/v1" untuk Ollama. apiKey boleh kosong untuk server lokal.
+func NewOpenAIProvider(baseURL, apiKey, model string, httpClient *http.Client) *OpenAIProvider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 120 * time.Second}
+	}
+	return &OpenAIProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: httpClient,
+	}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+// post mengirim request chat completion dan mengembalikan respons HTTP mentah.
+func (p *OpenAIProvider) post(ctx context.Context, prompt string, stream bool) (*http.Response, error) {
+	body, err := json.Marshal(chatCompletionRequest{
+		Model:       p.model,
+		Messages:    []chatMessage{{Role: "user", Content: prompt}},
+		Temperature: 0.7,
+		Stream:      stream,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	return p.httpClient.Do(httpReq)
+}
+
+func (p *OpenAIProvider) Generate(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	resp, err := p.post(ctx, req.Prompt, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var completion chatCompletionResponse
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return nil, fmt.Errorf("respons chat completion tidak valid (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if completion.Error != nil && completion.Error.Message != "" {
+			return nil, fmt.Errorf("chat completion gagal (HTTP %d): %s", resp.StatusCode, completion.Error.Message)
+		}
+		return nil, fmt.Errorf("chat completion gagal (HTTP %d)", resp.StatusCode)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("respons AI tidak valid atau kosong")
+	}
+
+	result := &LLMResponse{Text: completion.Choices[0].Message.Content}
+	if completion.Usage != nil {
+		result.PromptTokens = completion.Usage.PromptTokens
+		result.CompletionTokens = completion.Usage.CompletionTokens
+		result.TotalTokens = completion.Usage.TotalTokens
+	}
+	return result, nil
+}
+
+// GenerateStream membaca respons server-sent events ("data: {...}" hingga "data: [DONE]").
+func (p *OpenAIProvider) GenerateStream(ctx context.Context, req LLMRequest, onChunk func(chunk string) error) (*LLMResponse, error) {
+	resp, err := p.post(ctx, req.Prompt, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		var completion chatCompletionResponse
+		if json.Unmarshal(respBody, &completion) == nil && completion.Error != nil && completion.Error.Message != "" {
+			return nil, fmt.Errorf("chat completion gagal (HTTP %d): %s", resp.StatusCode, completion.Error.Message)
+		}
+		return nil, fmt.Errorf("chat completion gagal (HTTP %d)", resp.StatusCode)
+	}
+
+	result := &LLMResponse{}
+	var sb strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("potongan stream chat completion tidak valid: %w", err)
+		}
+		if chunk.Usage != nil {
+			result.PromptTokens = chunk.Usage.PromptTokens
+			result.CompletionTokens = chunk.Usage.CompletionTokens
+			result.TotalTokens = chunk.Usage.TotalTokens
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		text := chunk.Choices[0].Delta.Content
+		sb.WriteString(text)
+		if err := onChunk(text); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Text = sb.String()
+	return result, nil
+}
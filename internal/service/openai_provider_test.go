@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIProviderGenerate(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantText   string
+		wantTokens int
+		wantErr    string
+	}{
+		{
+			name:       "success",
+			status:     http.StatusOK,
+			body:       `{"choices":[{"message":{"role":"assistant","content":"[{\"title\":\"Belajar\"}]"}}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+			wantText:   `[{"title":"Belajar"}]`,
+			wantTokens: 15,
+		},
+		{
+			name:     "success without usage",
+			status:   http.StatusOK,
+			body:     `{"choices":[{"message":{"role":"assistant","content":"halo"}}]}`,
+			wantText: "halo",
+		},
+		{
+			name:    "error with message",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error":{"message":"rate limit reached"}}`,
+			wantErr: "HTTP 429): rate limit reached",
+		},
+		{
+			name:    "error without message",
+			status:  http.StatusInternalServerError,
+			body:    `{}`,
+			wantErr: "chat completion gagal (HTTP 500)",
+		},
+		{
+			name:    "body is not json",
+			status:  http.StatusBadGateway,
+			body:    `<html>bad gateway</html>`,
+			wantErr: "respons chat completion tidak valid (HTTP 502)",
+		},
+		{
+			name:    "no choices",
+			status:  http.StatusOK,
+			body:    `{"choices":[]}`,
+			wantErr: "respons AI tidak valid atau kosong",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got chatCompletionRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/chat/completions" {
+					t.Errorf("path = %q, want /v1/chat/completions", r.URL.Path)
+				}
+				if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+					t.Errorf("Authorization = %q, want Bearer secret", auth)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			provider := NewOpenAIProvider(server.URL+"/v1/", "secret", "test-model", server.Client())
+			resp, err := provider.Generate(context.Background(), LLMRequest{Kind: KindRoadmap, Prompt: "buat roadmap"})
+
+			if got.Model != "test-model" || len(got.Messages) != 1 || got.Messages[0].Content != "buat roadmap" {
+				t.Errorf("unexpected request body: %+v", got)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Text != tt.wantText || resp.TotalTokens != tt.wantTokens {
+				t.Errorf("got text %q tokens %d, want %q tokens %d", resp.Text, resp.TotalTokens, tt.wantText, tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestOpenAIProviderWithoutAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("Authorization = %q, want none for a local server", auth)
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"halo"}}]}`)
+	}))
+	defer server.Close()
+
+	resp, err := NewOpenAIProvider(server.URL, "", "llama3.1", nil).Generate(context.Background(), LLMRequest{Prompt: "hai"})
+	if err != nil || resp.Text != "halo" {
+		t.Fatalf("got %+v, %v", resp, err)
+	}
+}
+
+func TestOpenAIProviderGenerateStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		events     []string
+		wantChunks []string
+		wantTokens int
+		wantErr    string
+	}{
+		{
+			name:   "chunks until done",
+			status: http.StatusOK,
+			events: []string{
+				`{"choices":[{"delta":{"role":"assistant","content":""}}]}`,
+				`{"choices":[{"delta":{"content":"Progres "}}]}`,
+				`{"choices":[{"delta":{"content":"yang bagus"}}]}`,
+				`{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}`,
+				`[DONE]`,
+				`{"choices":[{"delta":{"content":"diabaikan"}}]}`,
+			},
+			wantChunks: []string{"Progres ", "yang bagus"},
+			wantTokens: 7,
+		},
+		{
+			name:    "invalid chunk",
+			status:  http.StatusOK,
+			events:  []string{`{"choices":[{"delta":{"content":"a"}}]}`, `{not json`},
+			wantErr: "potongan stream chat completion tidak valid",
+		},
+		{
+			name:    "error status",
+			status:  http.StatusUnauthorized,
+			wantErr: "HTTP 401): invalid api key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req chatCompletionRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Stream {
+					t.Errorf("expected a stream request, got %+v (err %v)", req, err)
+				}
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+					fmt.Fprint(w, `{"error":{"message":"invalid api key"}}`)
+					return
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, event := range tt.events {
+					fmt.Fprintf(w, "data: %s\n\n", event)
+				}
+			}))
+			defer server.Close()
+
+			provider := NewOpenAIProvider(server.URL, "", "test-model", server.Client())
+			var chunks []string
+			resp, err := provider.GenerateStream(context.Background(), LLMRequest{Kind: KindReviewFeedback, Prompt: "review"}, func(chunk string) error {
+				chunks = append(chunks, chunk)
+				return nil
+			})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(chunks, "|") != strings.Join(tt.wantChunks, "|") {
+				t.Errorf("chunks = %q, want %q", chunks, tt.wantChunks)
+			}
+			if resp.Text != strings.Join(tt.wantChunks, "") || resp.TotalTokens != tt.wantTokens {
+				t.Errorf("got text %q tokens %d", resp.Text, resp.TotalTokens)
+			}
+		})
+	}
+}
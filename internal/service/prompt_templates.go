@@ -0,0 +1,91 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+)
+
+//go:embed prompts
+var promptFS embed.FS
+
+// Locale yang didukung untuk prompt dan konten AI.
+const (
+	LocaleIndonesian = "id"
+	LocaleEnglish    = "en"
+	DefaultLocale    = LocaleIndonesian
+)
+
+var SupportedLocales = []string{LocaleIndonesian, LocaleEnglish}
+
+// Nama template yang wajib ada untuk setiap locale.
+const (
+	promptRoadmap          = "roadmap"
+	promptDailyTasks       = "daily_tasks"
+	promptReviewFeedback   = "review_feedback"
+	promptRepair           = "repair"
+	promptFeedbackFallback = "feedback_fallback"
+	promptStepSplit        = "step_split"
+	promptStepRephrase     = "step_rephrase"
+	promptRoadmapTail      = "roadmap_tail"
+)
+
+var requiredPrompts = []string{promptRoadmap, promptDailyTasks, promptReviewFeedback, promptRepair, promptFeedbackFallback,
+	promptStepSplit, promptStepRephrase, promptRoadmapTail}
+
+// IsSupportedLocale memeriksa apakah locale punya set prompt.
+func IsSupportedLocale(locale string) bool {
+	for _, l := range SupportedLocales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// promptSet adalah satu versi template prompt (prompts/<versi>/<locale>/<nama>.tmpl).
+type promptSet struct {
+	version   string
+	templates map[string]*template.Template // key: "<locale>/<nama>"
+}
+
+func loadPromptSet(version string) (*promptSet, error) {
+	set := &promptSet{version: version, templates: map[string]*template.Template{}}
+
+	for _, locale := range SupportedLocales {
+		for _, name := range requiredPrompts {
+			file := path.Join("prompts", version, locale, name+".tmpl")
+			content, err := fs.ReadFile(promptFS, file)
+			if err != nil {
+				return nil, fmt.Errorf("template prompt %s tidak ditemukan: %w", file, err)
+			}
+			tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+			if err != nil {
+				return nil, fmt.Errorf("gagal mem-parsing template prompt %s: %w", file, err)
+			}
+			set.templates[locale+"/"+name] = tmpl
+		}
+	}
+	return set, nil
+}
+
+// render mengisi template `name` untuk locale; locale yang tidak dikenal jatuh ke DefaultLocale.
+func (p *promptSet) render(locale, name string, data any) (string, error) {
+	if !IsSupportedLocale(locale) {
+		locale = DefaultLocale
+	}
+	tmpl, ok := p.templates[locale+"/"+name]
+	if !ok {
+		return "", fmt.Errorf("template prompt %s/%s tidak dikenal", locale, name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("gagal mengisi template prompt %s/%s: %w", locale, name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
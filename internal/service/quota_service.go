@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+// QuotaExceededError dikembalikan ketika user sudah menghabiskan kuota generasi AI.
+type QuotaExceededError struct {
+	Period     string // "daily" atau "monthly"
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("kuota AI %s (%d generasi) sudah habis", e.Period, e.Limit)
+}
+
+// AIUsageReport adalah ringkasan kuota untuk endpoint GET /api/me/ai-usage.
+// Limit 0 berarti tidak dibatasi; Remaining bernilai nil dalam kasus itu.
+type AIUsageReport struct {
+	DailyLimit       int       `json:"daily_limit"`
+	DailyUsed        int       `json:"daily_used"`
+	DailyRemaining   *int      `json:"daily_remaining"`
+	DailyResetsAt    time.Time `json:"daily_resets_at"`
+	MonthlyLimit     int       `json:"monthly_limit"`
+	MonthlyUsed      int       `json:"monthly_used"`
+	MonthlyRemaining *int      `json:"monthly_remaining"`
+	MonthlyResetsAt  time.Time `json:"monthly_resets_at"`
+}
+
+type QuotaService struct {
+	usageRepo           *repository.AIUsageRepository
+	defaultDailyLimit   int
+	defaultMonthlyLimit int
+}
+
+func NewQuotaService(usageRepo *repository.AIUsageRepository) *QuotaService {
+	return &QuotaService{
+		usageRepo:           usageRepo,
+		defaultDailyLimit:   intFromConfig("AI_DAILY_GENERATION_LIMIT", 20),
+		defaultMonthlyLimit: intFromConfig("AI_MONTHLY_GENERATION_LIMIT", 300),
+	}
+}
+
+// ConsumeGeneration memakai satu kuota generasi AI untuk user, atau mengembalikan
+// *QuotaExceededError jika kuota harian/bulanan sudah habis.
+func (s *QuotaService) ConsumeGeneration(ctx context.Context, userID string) error {
+	dailyLimit, monthlyLimit, err := s.limitsFor(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	usage, allowed, err := s.usageRepo.ConsumeGeneration(ctx, userID, now, dailyLimit, monthlyLimit)
+	if err != nil {
+		return err
+	}
+	if allowed {
+		return nil
+	}
+
+	// Kuota bulanan yang habis lebih menentukan karena reset-nya lebih lama
+	if monthlyLimit > 0 && usage.MonthlyUsed >= monthlyLimit {
+		return &QuotaExceededError{Period: "monthly", Limit: monthlyLimit, RetryAfter: startOfNextMonth(now).Sub(now)}
+	}
+	return &QuotaExceededError{Period: "daily", Limit: dailyLimit, RetryAfter: startOfNextDay(now).Sub(now)}
+}
+
+type quotaChargeKey struct{}
+
+// quotaCharge menunda pemakaian kuota sampai provider AI benar-benar akan dipanggil. Kuota
+// dipakai paling banyak sekali per operasi, termasuk percobaan perbaikan dan beberapa goal
+// dalam satu jadwal harian. Template cadangan dan sirkuit yang terbuka tidak memakai kuota.
+type quotaCharge struct {
+	once    sync.Once
+	err     error
+	consume func(ctx context.Context) error
+}
+
+// WithGenerationCharge menandai ctx agar panggilan provider AI pertama di dalamnya memakai
+// satu kuota generasi user.
+func (s *QuotaService) WithGenerationCharge(ctx context.Context, userID string) context.Context {
+	charge := &quotaCharge{consume: func(ctx context.Context) error { return s.ConsumeGeneration(ctx, userID) }}
+	return context.WithValue(ctx, quotaChargeKey{}, charge)
+}
+
+// chargeQuota memakai kuota yang ditandai lewat WithGenerationCharge, jika ada.
+func chargeQuota(ctx context.Context) error {
+	charge, ok := ctx.Value(quotaChargeKey{}).(*quotaCharge)
+	if !ok {
+		return nil
+	}
+	charge.once.Do(func() { charge.err = charge.consume(ctx) })
+	return charge.err
+}
+
+func isQuotaExceeded(err error) bool {
+	var quotaErr *QuotaExceededError
+	return errors.As(err, &quotaErr)
+}
+
+// GetUsage mengembalikan pemakaian dan sisa kuota user saat ini.
+func (s *QuotaService) GetUsage(ctx context.Context, userID string) (*AIUsageReport, error) {
+	dailyLimit, monthlyLimit, err := s.limitsFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	usage, err := s.usageRepo.GetUsage(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AIUsageReport{
+		DailyLimit:       dailyLimit,
+		DailyUsed:        usage.DailyUsed,
+		DailyRemaining:   remaining(dailyLimit, usage.DailyUsed),
+		DailyResetsAt:    startOfNextDay(now),
+		MonthlyLimit:     monthlyLimit,
+		MonthlyUsed:      usage.MonthlyUsed,
+		MonthlyRemaining: remaining(monthlyLimit, usage.MonthlyUsed),
+		MonthlyResetsAt:  startOfNextMonth(now),
+	}, nil
+}
+
+func (s *QuotaService) limitsFor(ctx context.Context, userID string) (int, int, error) {
+	dailyOverride, monthlyOverride, err := s.usageRepo.GetLimitOverrides(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	daily, monthly := s.defaultDailyLimit, s.defaultMonthlyLimit
+	if dailyOverride != nil {
+		daily = *dailyOverride
+	}
+	if monthlyOverride != nil {
+		monthly = *monthlyOverride
+	}
+	return daily, monthly, nil
+}
+
+func remaining(limit, used int) *int {
+	if limit <= 0 {
+		return nil
+	}
+	left := limit - used
+	if left < 0 {
+		left = 0
+	}
+	return &left
+}
+
+func startOfNextDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+func startOfNextMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// intFromConfig membaca angka dari environment, atau def jika kosong/tidak valid.
+func intFromConfig(key string, def int) int {
+	if v, err := strconv.Atoi(config.Get(key)); err == nil {
+		return v
+	}
+	return def
+}
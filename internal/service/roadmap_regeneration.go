@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+// Mode regenerasi satu langkah roadmap.
+const (
+	RegenerateSplit    = "split"    // Pecah langkah menjadi beberapa langkah yang lebih kecil
+	RegenerateRephrase = "rephrase" // Rumuskan ulang judul langkah
+)
+
+var (
+	ErrInvalidRegenerateMode = errors.New("mode regenerasi harus split atau rephrase")
+	ErrStepResolved          = errors.New("langkah yang sudah tuntas tidak bisa dibuat ulang")
+)
+
+// ProposalError dikembalikan ketika usulan langkah yang dikirim klien tidak lolos validasi.
+type ProposalError struct {
+	Problems []string
+}
+
+func (e *ProposalError) Error() string {
+	return fmt.Sprintf("usulan langkah tidak valid: %s", strings.Join(e.Problems, "; "))
+}
+
+// RegenerateRequest adalah permintaan regenerasi langkah atau sisa roadmap.
+type RegenerateRequest struct {
+	Mode         string                   // split atau rephrase; hanya untuk regenerasi satu langkah
+	Instructions string                   // Catatan tambahan untuk AI, opsional
+	Preview      bool                     // true: kembalikan usulan tanpa menyimpan apa pun
+	Proposal     []repository.RoadmapStep // Usulan dari preview sebelumnya; jika diisi, AI tidak dipanggil
+}
+
+// RegenerationResult adalah usulan hasil regenerasi dan, jika sudah disimpan, roadmap terbaru.
+type RegenerationResult struct {
+	Preview  bool                     `json:"preview"`
+	Proposal []repository.RoadmapStep `json:"proposal"`
+	Replaced []repository.RoadmapStep `json:"replaced"`        // Langkah yang diganti (atau akan diganti) oleh usulan
+	Steps    []repository.RoadmapStep `json:"steps,omitempty"` // Roadmap lengkap setelah disimpan, dalam bentuk pohon
+}
+
+// RegenerateStep membuat ulang satu langkah yang belum tuntas dengan AI.
+//   - rephrase: judul langkah diganti dengan rumusan baru.
+//   - split pada langkah utama: usulan menjadi sub-langkahnya, menggantikan sub-langkah yang masih pending.
+//   - split pada sub-langkah: sub-langkah diganti beberapa sub-langkah baru di posisi yang sama;
+//     tugas yang terhubung dipindahkan ke sub-langkah baru pertama.
+func (s *GoalService) RegenerateStep(ctx context.Context, userID, stepID string, req RegenerateRequest) (*RegenerationResult, error) {
+	schema := stepSplitSchema
+	switch req.Mode {
+	case RegenerateSplit:
+	case RegenerateRephrase:
+		schema = stepRephraseSchema
+	default:
+		return nil, ErrInvalidRegenerateMode
+	}
+
+	step, err := s.roadmapRepo.GetStepByID(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	goal, err := s.goalRepo.GetGoalByID(ctx, userID, step.GoalID)
+	if err != nil {
+		return nil, err
+	}
+	if stepResolved(step.Status) {
+		return nil, ErrStepResolved
+	}
+	steps, err := s.roadmapRepo.GetRoadmapStepsByGoalID(ctx, goal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Split pada langkah utama tidak menghapus langkah itu sendiri, hanya sub-langkahnya yang masih pending
+	splitParent := req.Mode == RegenerateSplit && step.ParentID == nil
+	replaced := []repository.RoadmapStep{*step}
+	if splitParent {
+		replaced = []repository.RoadmapStep{}
+	}
+	parentTitle := ""
+	for _, other := range steps {
+		if step.ParentID != nil && other.ID == *step.ParentID {
+			parentTitle = other.Title
+		}
+		if splitParent && other.ParentID != nil && *other.ParentID == step.ID && other.Status == repository.StepStatusPending {
+			replaced = append(replaced, other)
+		}
+	}
+
+	proposal, err := s.proposalFor(ctx, userID, req, schema, func(ctx context.Context) ([]repository.RoadmapStep, error) {
+		ctx = withAIScope(ctx, userID, goal.ID)
+		if req.Mode == RegenerateRephrase {
+			return s.aiService.GenerateStepRephrase(ctx, goal.Description, step.Title, parentTitle, req.Instructions)
+		}
+		return s.aiService.GenerateStepSplit(ctx, goal.Description, step.Title, parentTitle, req.Instructions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RegenerationResult{Preview: req.Preview, Proposal: proposal, Replaced: replaced}
+	if req.Preview {
+		return result, nil
+	}
+
+	switch {
+	case req.Mode == RegenerateRephrase:
+		err = s.roadmapRepo.UpdateStepTitle(ctx, userID, step.ID, proposal[0].Title)
+	case splitParent:
+		err = s.roadmapRepo.ReplaceChildSteps(ctx, goal.ID, step.ID, proposal)
+	default:
+		// Progres sub-langkah lama (tugas yang terhubung) ikut pindah ke sub-langkah baru pertama
+		proposal[0].Status = step.Status
+		err = s.roadmapRepo.ReplaceStep(ctx, step, proposal)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Steps, err = s.roadmapRepo.GetRoadmapStepsByGoalID(ctx, goal.ID); err != nil {
+		return nil, err
+	}
+	result.Steps = buildStepTree(result.Steps)
+	return result, nil
+}
+
+// RegenerateRoadmapTail membuat ulang bagian roadmap yang belum dikerjakan: langkah utama setelah
+// langkah terakhir yang sudah dikerjakan (status bukan pending atau punya sub-langkah yang dikerjakan). Langkah yang sudah dikerjakan beserta
+// tugasnya dipertahankan; langkah baru ditambahkan setelahnya dan due_date dijadwalkan ulang.
+func (s *GoalService) RegenerateRoadmapTail(ctx context.Context, userID, goalID string, req RegenerateRequest) (*RegenerationResult, error) {
+	goal, err := s.goalRepo.GetGoalByID(ctx, userID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := s.roadmapRepo.GetRoadmapStepsByGoalID(ctx, goal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ekor roadmap dimulai setelah langkah terakhir yang sudah dikerjakan; langkah pending di
+	// depannya tetap dipertahankan agar urutan roadmap tidak berubah
+	tree := buildStepTree(steps)
+	tailStart := 0
+	for i, step := range tree {
+		if stepStarted(step) {
+			tailStart = i + 1
+		}
+	}
+	var keptTitles, replacedTitles, tailIDs []string
+	replaced := []repository.RoadmapStep{}
+	for i, step := range tree {
+		if i < tailStart {
+			keptTitles = append(keptTitles, step.Title)
+			continue
+		}
+		replaced = append(replaced, step)
+		replacedTitles = append(replacedTitles, step.Title)
+		tailIDs = append(tailIDs, step.ID)
+	}
+
+	proposal, err := s.proposalFor(ctx, userID, req, roadmapTailSchema, func(ctx context.Context) ([]repository.RoadmapStep, error) {
+		return s.aiService.GenerateRoadmapTail(withAIScope(ctx, userID, goal.ID), goal.Description, keptTitles, replacedTitles, req.Instructions, goal.TargetDate)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RegenerationResult{Preview: req.Preview, Proposal: proposal, Replaced: replaced}
+	if req.Preview {
+		return result, nil
+	}
+
+	if err := s.roadmapRepo.ReplaceRoadmapTail(ctx, goal.ID, tailIDs, proposal); err != nil {
+		return nil, err
+	}
+	steps, err = s.roadmapRepo.GetRoadmapStepsByGoalID(ctx, goal.ID)
+	if err != nil {
+		return nil, err
+	}
+	scheduleSteps(steps, time.Now(), goal.TargetDate)
+	if err := s.roadmapRepo.UpdateStepDueDates(ctx, steps); err != nil {
+		return nil, err
+	}
+	result.Steps = buildStepTree(steps)
+	return result, nil
+}
+
+// proposalFor memakai usulan dari klien (hasil preview sebelumnya) jika ada, sehingga yang
+// disimpan sama persis dengan yang sudah dilihat user. Jika tidak, AI diminta membuat usulan
+// baru. Preview tidak memakai kuota; satu kuota generasi terpakai ketika usulan disimpan.
+func (s *GoalService) proposalFor(ctx context.Context, userID string, req RegenerateRequest, schema outputSchema, generate func(ctx context.Context) ([]repository.RoadmapStep, error)) ([]repository.RoadmapStep, error) {
+	if len(req.Proposal) == 0 {
+		if req.Preview {
+			return generate(ctx)
+		}
+		return generate(s.quota.WithGenerationCharge(ctx, userID))
+	}
+
+	proposal, err := validateProposal(req.Proposal, schema)
+	if err != nil || req.Preview {
+		return proposal, err
+	}
+	if err := s.quota.ConsumeGeneration(ctx, userID); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// validateProposal memeriksa usulan dari klien dengan schema yang sama seperti output AI.
+// Hanya judul, estimasi, milestone, dan sub-langkah yang dipakai; urutan mengikuti posisi di array.
+func validateProposal(steps []repository.RoadmapStep, schema outputSchema) ([]repository.RoadmapStep, error) {
+	items := make([]aiItem, len(steps))
+	for i, step := range steps {
+		order := i + 1
+		items[i] = aiItem{StepOrder: &order, Title: step.Title, EstimatedDays: step.EstimatedDays, Milestone: step.IsMilestone}
+		for _, child := range step.Children {
+			items[i].SubSteps = append(items[i].SubSteps, aiItem{Title: child.Title, Milestone: child.IsMilestone})
+		}
+	}
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	validated, problems := schema.validate(string(raw))
+	if len(problems) > 0 {
+		return nil, &ProposalError{Problems: problems}
+	}
+	return stepsFromItems(validated), nil
+}
+
+// stepStarted memeriksa apakah langkah utama (bentuk pohon) atau salah satu sub-langkahnya
+// sudah mulai dikerjakan.
+func stepStarted(step repository.RoadmapStep) bool {
+	if step.Status != repository.StepStatusPending {
+		return true
+	}
+	for _, child := range step.Children {
+		if child.Status != repository.StepStatusPending {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package service
+
+import "github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+
+// buildStepTree menyusun langkah datar (terurut step_order) menjadi pohon: langkah utama
+// dengan sub-langkah di Children. Sub-langkah yang parent-nya tidak ada ikut dianggap
+// langkah utama agar tidak hilang dari respons.
+func buildStepTree(flat []repository.RoadmapStep) []repository.RoadmapStep {
+	children := map[string][]repository.RoadmapStep{}
+	exists := make(map[string]bool, len(flat))
+	for _, step := range flat {
+		exists[step.ID] = true
+	}
+	for _, step := range flat {
+		if step.ParentID != nil && exists[*step.ParentID] {
+			children[*step.ParentID] = append(children[*step.ParentID], step)
+		}
+	}
+
+	tree := []repository.RoadmapStep{}
+	for _, step := range flat {
+		if step.ParentID != nil && exists[*step.ParentID] {
+			continue
+		}
+		step.Children = children[step.ID]
+		tree = append(tree, step)
+	}
+	return tree
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+var (
+	ErrInvalidStepStatus = errors.New("status langkah roadmap tidak dikenal")
+	ErrInvalidTaskStatus = errors.New("status tugas tidak dikenal")
+)
+
+// StatusTransitionError dikembalikan ketika perubahan status langkah roadmap atau tugas
+// tidak diizinkan oleh tabel transisi.
+type StatusTransitionError struct {
+	Entity string // "roadmap_step" atau "task"
+	From   string
+	To     string
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("%s tidak bisa diubah dari %s ke %s", e.Entity, e.From, e.To)
+}
+
+// stepTransitions adalah status tujuan yang diizinkan dari setiap status langkah roadmap.
+// Langkah yang sudah tuntas bisa dibuka kembali.
+var stepTransitions = map[string][]string{
+	repository.StepStatusPending:    {repository.StepStatusInProgress, repository.StepStatusCompleted, repository.StepStatusSkipped},
+	repository.StepStatusInProgress: {repository.StepStatusPending, repository.StepStatusCompleted, repository.StepStatusSkipped},
+	repository.StepStatusCompleted:  {repository.StepStatusInProgress},
+	repository.StepStatusSkipped:    {repository.StepStatusPending, repository.StepStatusInProgress},
+}
+
+// taskTransitions adalah status tujuan yang diizinkan dari setiap status tugas. Tugas yang
+// terlewat masih boleh diselesaikan belakangan.
+var taskTransitions = map[string][]string{
+	repository.TaskStatusPending:   {repository.TaskStatusCompleted, repository.TaskStatusMissed},
+	repository.TaskStatusCompleted: {repository.TaskStatusPending},
+	repository.TaskStatusMissed:    {repository.TaskStatusPending, repository.TaskStatusCompleted},
+}
+
+func canTransition(transitions map[string][]string, from, to string) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// stepResolved bernilai true untuk langkah yang tidak lagi dikerjakan (completed atau skipped).
+func stepResolved(status string) bool {
+	return status == repository.StepStatusCompleted || status == repository.StepStatusSkipped
+}
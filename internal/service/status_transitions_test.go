@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+// TestStatusTransitions memeriksa setiap pasangan status, termasuk transisi ke status yang sama
+// dan status yang tidak dikenal. Pasangan yang tidak tercantum di allowed harus ditolak.
+func TestStatusTransitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		transitions map[string][]string
+		statuses    []string
+		allowed     [][2]string
+	}{
+		{
+			name:        "roadmap step",
+			transitions: stepTransitions,
+			statuses: []string{
+				repository.StepStatusPending, repository.StepStatusInProgress,
+				repository.StepStatusCompleted, repository.StepStatusSkipped, "archived", "",
+			},
+			allowed: [][2]string{
+				{"pending", "in_progress"},
+				{"pending", "completed"},
+				{"pending", "skipped"},
+				{"in_progress", "pending"},
+				{"in_progress", "completed"},
+				{"in_progress", "skipped"},
+				{"completed", "in_progress"},
+				{"skipped", "pending"},
+				{"skipped", "in_progress"},
+			},
+		},
+		{
+			name:        "task",
+			transitions: taskTransitions,
+			statuses: []string{
+				repository.TaskStatusPending, repository.TaskStatusCompleted, repository.TaskStatusMissed, "skipped", "",
+			},
+			allowed: [][2]string{
+				{"pending", "completed"},
+				{"pending", "missed"},
+				{"completed", "pending"},
+				{"missed", "pending"},
+				{"missed", "completed"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := make(map[[2]string]bool, len(tt.allowed))
+			for _, pair := range tt.allowed {
+				allowed[pair] = true
+			}
+
+			for _, from := range tt.statuses {
+				for _, to := range tt.statuses {
+					want := allowed[[2]string{from, to}]
+					if got := canTransition(tt.transitions, from, to); got != want {
+						t.Errorf("canTransition(%q, %q) = %v, want %v", from, to, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStepResolved(t *testing.T) {
+	tests := map[string]bool{
+		repository.StepStatusPending:    false,
+		repository.StepStatusInProgress: false,
+		repository.StepStatusCompleted:  true,
+		repository.StepStatusSkipped:    true,
+	}
+	for status, want := range tests {
+		if got := stepResolved(status); got != want {
+			t.Errorf("stepResolved(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+// StepProgress adalah progres satu langkah roadmap yang dihitung dari tugas-tugas yang terhubung.
+type StepProgress struct {
+	StepID          string  `json:"step_id"`
+	Status          string  `json:"status"`
+	CompletedTasks  int     `json:"completed_tasks"`
+	TotalTasks      int     `json:"total_tasks"`
+	CompletionRate  float64 `json:"completion_rate"`   // 0-1
+	ReadyToComplete bool    `json:"ready_to_complete"` // Kriteria selesai terpenuhi tapi langkah belum completed
+	AutoCompleted   bool    `json:"auto_completed"`    // Langkah baru saja ditandai completed otomatis
+}
+
+// stepCompletionRule menentukan kapan sebuah langkah dianggap selesai dari tugas-tugasnya.
+type stepCompletionRule struct {
+	minTasks int     // Minimal tugas completed
+	minRate  float64 // Minimal rasio tugas completed dibanding semua tugas langkah itu
+	auto     bool    // true: tandai completed otomatis; false: hanya tawarkan lewat ReadyToComplete
+}
+
+func stepCompletionRuleFromConfig() stepCompletionRule {
+	rate := 0.7
+	if v, err := strconv.ParseFloat(config.Get("STEP_COMPLETION_RATE"), 64); err == nil && v > 0 && v <= 1 {
+		rate = v
+	}
+	return stepCompletionRule{
+		minTasks: max(intFromConfig("STEP_COMPLETION_MIN_TASKS", 5), 1),
+		minRate:  rate,
+		auto:     config.Get("STEP_AUTO_COMPLETE") != "false",
+	}
+}
+
+func (r stepCompletionRule) met(progress *repository.StepTaskProgress) bool {
+	if progress.Total == 0 || progress.Completed < r.minTasks {
+		return false
+	}
+	return float64(progress.Completed)/float64(progress.Total) >= r.minRate
+}
+
+// GetStepProgress menghitung progres langkah roadmap milik user dari tugas-tugasnya.
+func (s *TaskService) GetStepProgress(ctx context.Context, userID, stepID string) (*StepProgress, error) {
+	step, err := s.roadmapRepo.GetStepByID(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.goalRepo.GetGoalByID(ctx, userID, step.GoalID); err != nil {
+		return nil, err
+	}
+	return s.stepProgress(ctx, step)
+}
+
+// advanceStep dipanggil setelah status tugas berubah. Jika kriteria selesai terpenuhi dan
+// auto-complete aktif, langkah ditandai completed (yang juga bisa menuntaskan goal-nya).
+func (s *TaskService) advanceStep(ctx context.Context, userID, stepID string) (*StepProgress, error) {
+	step, err := s.roadmapRepo.GetStepByID(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := s.stepProgress(ctx, step)
+	if err != nil || !progress.ReadyToComplete || !s.stepRule.auto {
+		return progress, err
+	}
+
+	if err := s.goals.UpdateRoadmapStepStatus(ctx, userID, stepID, repository.StepStatusCompleted); err != nil {
+		return nil, err
+	}
+	log.Printf("Langkah roadmap %s otomatis ditandai completed (%d/%d tugas selesai)", stepID, progress.CompletedTasks, progress.TotalTasks)
+	progress.Status = repository.StepStatusCompleted
+	progress.ReadyToComplete = false
+	progress.AutoCompleted = true
+	return progress, nil
+}
+
+func (s *TaskService) stepProgress(ctx context.Context, step *repository.RoadmapStep) (*StepProgress, error) {
+	counts, err := s.taskRepo.GetStepTaskProgress(ctx, step.ID)
+	if err != nil {
+		return nil, err
+	}
+	progress := &StepProgress{
+		StepID:         step.ID,
+		Status:         step.Status,
+		CompletedTasks: counts.Completed,
+		TotalTasks:     counts.Total,
+	}
+	if counts.Total > 0 {
+		progress.CompletionRate = math.Round(float64(counts.Completed)/float64(counts.Total)*100) / 100
+	}
+	progress.ReadyToComplete = !stepResolved(step.Status) && s.stepRule.met(counts)
+	return progress, nil
+}
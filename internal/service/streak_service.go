@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/config"
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+// StreakStats adalah ringkasan streak untuk GET /api/stats/streak.
+type StreakStats struct {
+	CurrentStreak       int                    `json:"current_streak"`
+	LongestStreak       int                    `json:"longest_streak"`
+	LastStreakDate      *time.Time             `json:"last_streak_date"`
+	FreezesAvailable    int                    `json:"freezes_available"`
+	CompletionThreshold float64                `json:"completion_threshold"`
+	History             []repository.StreakDay `json:"history,omitempty"`
+}
+
+type StreakService struct {
+	streakRepo  *repository.StreakRepository
+	threshold   float64 // Porsi minimal tugas selesai agar satu hari dihitung (0 < threshold <= 1)
+	freezeEvery int     // Dapat satu freeze setiap kelipatan sekian hari streak (0 = tidak pernah)
+	maxFreezes  int     // Batas freeze yang bisa disimpan
+	historyDays int     // Berapa hari riwayat yang dikembalikan endpoint statistik
+}
+
+func NewStreakService(streakRepo *repository.StreakRepository) *StreakService {
+	threshold := 0.7
+	if v, err := strconv.ParseFloat(config.Get("STREAK_COMPLETION_THRESHOLD"), 64); err == nil && v > 0 && v <= 1 {
+		threshold = v
+	}
+	return &StreakService{
+		streakRepo:  streakRepo,
+		threshold:   threshold,
+		freezeEvery: intFromConfig("STREAK_FREEZE_EVERY_DAYS", 7),
+		maxFreezes:  intFromConfig("STREAK_MAX_FREEZES", 2),
+		historyDays: 30,
+	}
+}
+
+// RecordDay mengevaluasi hari `day` dari ringkasan tugas hasil review dan memperbarui streak.
+func (s *StreakService) RecordDay(ctx context.Context, userID string, day time.Time, summary []repository.TaskSummary) (*StreakStats, error) {
+	day = truncateToDate(day)
+	completed, total := 0, 0
+	for _, item := range summary {
+		total += item.Count
+		if item.Status == "completed" {
+			completed += item.Count
+		}
+	}
+
+	state, err := s.streakRepo.UpdateStreak(ctx, userID, func(state *repository.StreakState) []repository.StreakDay {
+		return s.applyDay(state, day, completed, total)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.statsFor(state, day), nil
+}
+
+// GetStreak mengembalikan streak user per hari ini beserta riwayat beberapa hari terakhir.
+func (s *StreakService) GetStreak(ctx context.Context, userID string, now time.Time) (*StreakStats, error) {
+	today := truncateToDate(now)
+	state, err := s.streakRepo.GetStreakState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stats := s.statsFor(state, today)
+
+	stats.History, err = s.streakRepo.GetStreakDays(ctx, userID, today.AddDate(0, 0, -s.historyDays))
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// applyDay adalah aturan streak. Hari yang tidak mencapai target hanya dicatat; streak baru
+// benar-benar putus saat hari berikutnya yang dihitung datang dan celahnya tidak bisa ditutup
+// freeze (lihat effectiveStreak untuk nilai yang ditampilkan sebelum itu).
+func (s *StreakService) applyDay(state *repository.StreakState, day time.Time, completed, total int) []repository.StreakDay {
+	record := repository.StreakDay{Date: day, CompletedTasks: completed, TotalTasks: total, Outcome: repository.StreakOutcomeMissed}
+	qualifies := total > 0 && float64(completed)/float64(total) >= s.threshold
+
+	if state.LastStreakDate != nil && !day.After(*state.LastStreakDate) {
+		// Review ulang untuk hari yang sudah lewat / sudah dihitung: state tidak berubah
+		if day.Equal(*state.LastStreakDate) {
+			return nil
+		}
+		if qualifies {
+			record.Outcome = repository.StreakOutcomeCounted
+		}
+		return []repository.StreakDay{record}
+	}
+	if !qualifies {
+		return []repository.StreakDay{record}
+	}
+
+	record.Outcome = repository.StreakOutcomeCounted
+	days := []repository.StreakDay{record}
+
+	switch gap := missedDaysBetween(state.LastStreakDate, day); {
+	case state.LastStreakDate == nil || gap > state.Freezes:
+		state.CurrentStreak = 1
+	default:
+		// gap == 0 berarti hari berturut-turut; selain itu tutup hari yang terlewat dengan freeze
+		for i := 1; i <= gap; i++ {
+			days = append(days, repository.StreakDay{Date: state.LastStreakDate.AddDate(0, 0, i), Outcome: repository.StreakOutcomeFrozen})
+		}
+		state.Freezes -= gap
+		state.CurrentStreak++
+	}
+
+	state.LastStreakDate = &day
+	if state.CurrentStreak > state.LongestStreak {
+		state.LongestStreak = state.CurrentStreak
+	}
+	if s.freezeEvery > 0 && state.CurrentStreak%s.freezeEvery == 0 && state.Freezes < s.maxFreezes {
+		state.Freezes++
+	}
+	return days
+}
+
+func (s *StreakService) statsFor(state *repository.StreakState, today time.Time) *StreakStats {
+	return &StreakStats{
+		CurrentStreak:       effectiveStreak(state, today),
+		LongestStreak:       state.LongestStreak,
+		LastStreakDate:      state.LastStreakDate,
+		FreezesAvailable:    state.Freezes,
+		CompletionThreshold: s.threshold,
+	}
+}
+
+// effectiveStreak adalah streak yang masih "hidup" per hari ini: hari ini sendiri belum
+// dihitung gagal, dan hari terlewat sebelumnya masih bisa ditutup oleh freeze yang tersedia.
+func effectiveStreak(state *repository.StreakState, today time.Time) int {
+	if state.LastStreakDate == nil {
+		return 0
+	}
+	if missedDaysBetween(state.LastStreakDate, truncateToDate(today)) > state.Freezes {
+		return 0
+	}
+	return state.CurrentStreak
+}
+
+// missedDaysBetween menghitung jumlah hari di antara last dan day (tidak termasuk keduanya).
+func missedDaysBetween(last *time.Time, day time.Time) int {
+	if last == nil {
+		return 0
+	}
+	gap := int(day.Sub(*last).Hours()/24) - 1
+	if gap < 0 {
+		return 0
+	}
+	return gap
+}
+
+func truncateToDate(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+)
+
+func mustDate(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func mustDatePtr(s string) *time.Time {
+	t := mustDate(s)
+	return &t
+}
+
+func TestApplyDay(t *testing.T) {
+	svc := &StreakService{threshold: 0.7, freezeEvery: 7, maxFreezes: 2}
+
+	tests := []struct {
+		name      string
+		state     repository.StreakState
+		day       string
+		completed int
+		total     int
+		want      repository.StreakState
+		wantDays  []string // "tanggal outcome" dalam urutan yang dikembalikan
+	}{
+		{
+			name:      "first counted day starts the streak",
+			day:       "2026-03-10",
+			state:     repository.StreakState{},
+			completed: 3,
+			total:     3,
+			want:      repository.StreakState{CurrentStreak: 1, LongestStreak: 1, LastStreakDate: mustDatePtr("2026-03-10")},
+			wantDays:  []string{"2026-03-10 counted"},
+		},
+		{
+			name:      "consecutive day extends the streak",
+			state:     repository.StreakState{CurrentStreak: 3, LongestStreak: 5, LastStreakDate: mustDatePtr("2026-03-09")},
+			day:       "2026-03-10",
+			completed: 4,
+			total:     5,
+			want:      repository.StreakState{CurrentStreak: 4, LongestStreak: 5, LastStreakDate: mustDatePtr("2026-03-10")},
+			wantDays:  []string{"2026-03-10 counted"},
+		},
+		{
+			name:      "exactly at the threshold counts",
+			state:     repository.StreakState{CurrentStreak: 1, LongestStreak: 1, LastStreakDate: mustDatePtr("2026-03-09")},
+			day:       "2026-03-10",
+			completed: 7,
+			total:     10,
+			want:      repository.StreakState{CurrentStreak: 2, LongestStreak: 2, LastStreakDate: mustDatePtr("2026-03-10")},
+			wantDays:  []string{"2026-03-10 counted"},
+		},
+		{
+			name:      "gap covered by freezes",
+			state:     repository.StreakState{CurrentStreak: 4, LongestStreak: 4, LastStreakDate: mustDatePtr("2026-03-07"), Freezes: 2},
+			day:       "2026-03-10",
+			completed: 3,
+			total:     3,
+			want:      repository.StreakState{CurrentStreak: 5, LongestStreak: 5, LastStreakDate: mustDatePtr("2026-03-10"), Freezes: 0},
+			wantDays:  []string{"2026-03-10 counted", "2026-03-08 frozen", "2026-03-09 frozen"},
+		},
+		{
+			name:      "gap larger than freezes restarts the streak",
+			state:     repository.StreakState{CurrentStreak: 4, LongestStreak: 6, LastStreakDate: mustDatePtr("2026-03-06"), Freezes: 2},
+			day:       "2026-03-10",
+			completed: 3,
+			total:     3,
+			want:      repository.StreakState{CurrentStreak: 1, LongestStreak: 6, LastStreakDate: mustDatePtr("2026-03-10"), Freezes: 2},
+			wantDays:  []string{"2026-03-10 counted"},
+		},
+		{
+			name:      "below threshold is only recorded",
+			state:     repository.StreakState{CurrentStreak: 2, LongestStreak: 2, LastStreakDate: mustDatePtr("2026-03-09"), Freezes: 1},
+			day:       "2026-03-10",
+			completed: 2,
+			total:     3,
+			want:      repository.StreakState{CurrentStreak: 2, LongestStreak: 2, LastStreakDate: mustDatePtr("2026-03-09"), Freezes: 1},
+			wantDays:  []string{"2026-03-10 missed"},
+		},
+		{
+			name:     "day without tasks is missed",
+			state:    repository.StreakState{CurrentStreak: 2, LongestStreak: 2, LastStreakDate: mustDatePtr("2026-03-09")},
+			day:      "2026-03-10",
+			want:     repository.StreakState{CurrentStreak: 2, LongestStreak: 2, LastStreakDate: mustDatePtr("2026-03-09")},
+			wantDays: []string{"2026-03-10 missed"},
+		},
+		{
+			name:      "same-day replay changes nothing",
+			state:     repository.StreakState{CurrentStreak: 2, LongestStreak: 2, LastStreakDate: mustDatePtr("2026-03-10")},
+			day:       "2026-03-10",
+			completed: 3,
+			total:     3,
+			want:      repository.StreakState{CurrentStreak: 2, LongestStreak: 2, LastStreakDate: mustDatePtr("2026-03-10")},
+		},
+		{
+			name:      "replay of an older day is recorded without touching the streak",
+			state:     repository.StreakState{CurrentStreak: 2, LongestStreak: 2, LastStreakDate: mustDatePtr("2026-03-10")},
+			day:       "2026-03-08",
+			completed: 3,
+			total:     3,
+			want:      repository.StreakState{CurrentStreak: 2, LongestStreak: 2, LastStreakDate: mustDatePtr("2026-03-10")},
+			wantDays:  []string{"2026-03-08 counted"},
+		},
+		{
+			name:      "every seventh day earns a freeze",
+			state:     repository.StreakState{CurrentStreak: 6, LongestStreak: 6, LastStreakDate: mustDatePtr("2026-03-09")},
+			day:       "2026-03-10",
+			completed: 3,
+			total:     3,
+			want:      repository.StreakState{CurrentStreak: 7, LongestStreak: 7, LastStreakDate: mustDatePtr("2026-03-10"), Freezes: 1},
+			wantDays:  []string{"2026-03-10 counted"},
+		},
+		{
+			name:      "freezes are capped",
+			state:     repository.StreakState{CurrentStreak: 13, LongestStreak: 20, LastStreakDate: mustDatePtr("2026-03-09"), Freezes: 2},
+			day:       "2026-03-10",
+			completed: 3,
+			total:     3,
+			want:      repository.StreakState{CurrentStreak: 14, LongestStreak: 20, LastStreakDate: mustDatePtr("2026-03-10"), Freezes: 2},
+			wantDays:  []string{"2026-03-10 counted"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := tt.state
+			days := svc.applyDay(&state, mustDate(tt.day), tt.completed, tt.total)
+
+			if state.CurrentStreak != tt.want.CurrentStreak || state.LongestStreak != tt.want.LongestStreak || state.Freezes != tt.want.Freezes {
+				t.Errorf("state = %+v, want %+v", state, tt.want)
+			}
+			if (state.LastStreakDate == nil) != (tt.want.LastStreakDate == nil) ||
+				(state.LastStreakDate != nil && !state.LastStreakDate.Equal(*tt.want.LastStreakDate)) {
+				t.Errorf("last streak date = %v, want %v", state.LastStreakDate, tt.want.LastStreakDate)
+			}
+
+			var got []string
+			for _, d := range days {
+				got = append(got, d.Date.Format("2006-01-02")+" "+d.Outcome)
+			}
+			if len(got) != len(tt.wantDays) {
+				t.Fatalf("days = %q, want %q", got, tt.wantDays)
+			}
+			for i := range got {
+				if got[i] != tt.wantDays[i] {
+					t.Errorf("days = %q, want %q", got, tt.wantDays)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestEffectiveStreak(t *testing.T) {
+	tests := []struct {
+		name  string
+		state repository.StreakState
+		today time.Time
+		want  int
+	}{
+		{name: "no streak yet", today: mustDate("2026-03-10"), want: 0},
+		{name: "counted today", state: repository.StreakState{CurrentStreak: 3, LastStreakDate: mustDatePtr("2026-03-10")}, today: mustDate("2026-03-10"), want: 3},
+		{name: "today not reviewed yet", state: repository.StreakState{CurrentStreak: 3, LastStreakDate: mustDatePtr("2026-03-09")}, today: mustDate("2026-03-10").Add(20 * time.Hour), want: 3},
+		{name: "missed day covered by a freeze", state: repository.StreakState{CurrentStreak: 3, LastStreakDate: mustDatePtr("2026-03-08"), Freezes: 1}, today: mustDate("2026-03-10"), want: 3},
+		{name: "missed days beyond freezes", state: repository.StreakState{CurrentStreak: 3, LastStreakDate: mustDatePtr("2026-03-07"), Freezes: 1}, today: mustDate("2026-03-10"), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := effectiveStreak(&tt.state, tt.today); got != tt.want {
+				t.Errorf("effectiveStreak = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// StubProvider mengembalikan konten statis tanpa jaringan, untuk CI dan pengembangan lokal.
+type StubProvider struct{}
+
+func NewStubProvider() *StubProvider {
+	return &StubProvider{}
+}
+
+func (p *StubProvider) Name() string {
+	return "stub"
+}
+
+func (p *StubProvider) Generate(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	switch req.Kind {
+	case KindRoadmap:
+		return &LLMResponse{Text: `[
+			{"step_order": 1, "title": "Pelajari dasar-dasar dan kumpulkan referensi", "estimated_days": 7},
+			{"step_order": 2, "title": "Susun rencana belajar dan target mingguan", "estimated_days": 3},
+			{"step_order": 3, "title": "Kerjakan proyek latihan pertama", "estimated_days": 14, "sub_steps": [
+				{"title": "Tentukan ruang lingkup proyek"},
+				{"title": "Bangun versi pertama"},
+				{"title": "Minta masukan dari orang lain"}
+			]},
+			{"step_order": 4, "title": "Evaluasi hasil dan perbaiki kekurangan", "estimated_days": 7, "milestone": true}
+		]`}, nil
+	case KindDailyTasks:
+		return &LLMResponse{Text: `[
+			{"title": "Baca satu materi inti selama 30 menit"},
+			{"title": "Catat tiga poin penting dari materi tersebut"},
+			{"title": "Praktikkan satu latihan kecil"}
+		]`}, nil
+	case KindStepSplit:
+		return &LLMResponse{Text: `[
+			{"title": "Kumpulkan bahan yang dibutuhkan"},
+			{"title": "Kerjakan bagian inti"},
+			{"title": "Periksa dan rapikan hasilnya"}
+		]`}, nil
+	case KindStepRephrase:
+		return &LLMResponse{Text: `[{"title": "Selesaikan satu bagian kecil yang jelas hasilnya"}]`}, nil
+	case KindRoadmapTail:
+		return &LLMResponse{Text: `[
+			{"step_order": 1, "title": "Lanjutkan latihan dengan proyek yang lebih kecil", "estimated_days": 7},
+			{"step_order": 2, "title": "Evaluasi hasil dan tentukan langkah berikutnya", "estimated_days": 3, "milestone": true}
+		]`}, nil
+	case KindReviewFeedback:
+		return &LLMResponse{Text: "Kerja bagus hari ini! Setiap langkah kecil membawamu lebih dekat ke tujuan. Istirahat yang cukup dan lanjutkan besok."}, nil
+	default:
+		return nil, fmt.Errorf("stub provider tidak mendukung jenis permintaan %q", req.Kind)
+	}
+}
+
+// GenerateStream mengirim respons statis kata demi kata agar alur streaming bisa diuji offline.
+func (p *StubProvider) GenerateStream(ctx context.Context, req LLMRequest, onChunk func(chunk string) error) (*LLMResponse, error) {
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	words := strings.SplitAfter(resp.Text, " ")
+	for _, word := range words {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := onChunk(word); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
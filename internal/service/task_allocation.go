@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// goalPlan adalah satu goal aktif beserta langkah roadmap yang sedang dikerjakan
+// dan jumlah tugas yang dialokasikan untuknya hari ini.
+type goalPlan struct {
+	Goal      repository.Goal
+	Step      *repository.RoadmapStep
+	TaskCount int
+}
+
+// buildGoalPlans memasangkan setiap goal aktif dengan langkah roadmap berikutnya yang belum selesai.
+// Goal yang semua langkahnya sudah selesai (nextStep mengembalikan pgx.ErrNoRows) dilewati sehingga
+// tidak ikut mendapat jatah tugas.
+func buildGoalPlans(ctx context.Context, goals []repository.Goal, nextStep func(ctx context.Context, goalID string) (*repository.RoadmapStep, error)) ([]goalPlan, error) {
+	var plans []goalPlan
+	for _, goal := range goals {
+		step, err := nextStep(ctx, goal.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("[DEBUG] Semua langkah roadmap goal %s sudah selesai.", goal.ID)
+			continue
+		}
+		if err != nil {
+			log.Printf("[DEBUG] Error saat mencari langkah roadmap: %v", err)
+			return nil, err
+		}
+		plans = append(plans, goalPlan{Goal: goal, Step: step})
+	}
+	return plans, nil
+}
+
+// allocateDailyTasks membagi `total` tugas harian ke setiap goal sebanding dengan priority-nya.
+// Setiap goal mendapat minimal satu tugas selama total mencukupi; sisanya dibagi dengan metode
+// sisa terbesar (largest remainder). Jika total lebih kecil dari jumlah goal, goal dengan
+// priority tertinggi didahulukan. Urutan plans dipertahankan.
+func allocateDailyTasks(plans []goalPlan, total int) {
+	if len(plans) == 0 || total <= 0 {
+		return
+	}
+
+	// Indeks diurutkan berdasarkan priority (stabil, sehingga goal yang lebih lama menang saat seri)
+	order := make([]int, len(plans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return plans[order[a]].Goal.Priority > plans[order[b]].Goal.Priority })
+
+	if total <= len(plans) {
+		for rank, i := range order {
+			if rank < total {
+				plans[i].TaskCount = 1
+			} else {
+				plans[i].TaskCount = 0
+			}
+		}
+		return
+	}
+
+	weightSum := 0
+	for _, p := range plans {
+		weightSum += weight(p)
+	}
+
+	remaining := total - len(plans)
+	remainders := make([]int, len(plans)) // Sisa pembagian dalam satuan 1/weightSum
+	assigned := 0
+	for i, p := range plans {
+		share := remaining * weight(p)
+		plans[i].TaskCount = 1 + share/weightSum
+		remainders[i] = share % weightSum
+		assigned += share / weightSum
+	}
+
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for k := 0; k < remaining-assigned; k++ {
+		plans[order[k]].TaskCount++
+	}
+}
+
+func weight(p goalPlan) int {
+	if p.Goal.Priority < repository.MinGoalPriority {
+		return repository.MinGoalPriority
+	}
+	return p.Goal.Priority
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// plansWithPriorities membuat satu goalPlan per priority, dengan ID "g0", "g1", dst.
+func plansWithPriorities(priorities ...int) []goalPlan {
+	plans := make([]goalPlan, len(priorities))
+	for i, priority := range priorities {
+		plans[i] = goalPlan{Goal: repository.Goal{ID: fmt.Sprintf("g%d", i), Priority: priority}}
+	}
+	return plans
+}
+
+func taskCounts(plans []goalPlan) []int {
+	counts := make([]int, len(plans))
+	for i, p := range plans {
+		counts[i] = p.TaskCount
+	}
+	return counts
+}
+
+func TestAllocateDailyTasks(t *testing.T) {
+	tests := []struct {
+		name       string
+		priorities []int
+		total      int
+		want       []int
+	}{
+		{name: "single goal takes everything", priorities: []int{5}, total: 5, want: []int{5}},
+		{name: "equal weights split evenly", priorities: []int{5, 5}, total: 6, want: []int{3, 3}},
+		{name: "uneven weights", priorities: []int{3, 1}, total: 6, want: []int{4, 2}},
+		{name: "uneven weights across three goals", priorities: []int{10, 5, 1}, total: 10, want: []int{5, 3, 2}},
+		{name: "largest remainder gets the leftover", priorities: []int{2, 1}, total: 4, want: []int{2, 2}},
+		{name: "priority below the minimum is clamped", priorities: []int{0, 1}, total: 4, want: []int{2, 2}},
+		{name: "fewer slots than goals favours higher priority", priorities: []int{1, 5, 3}, total: 2, want: []int{0, 1, 1}},
+		{name: "ties keep the original order", priorities: []int{4, 4, 4}, total: 1, want: []int{1, 0, 0}},
+		{name: "exactly one slot per goal", priorities: []int{1, 9}, total: 2, want: []int{1, 1}},
+		{name: "zero total leaves counts untouched", priorities: []int{3, 1}, total: 0, want: []int{0, 0}},
+		{name: "no plans", total: 5, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plans := plansWithPriorities(tt.priorities...)
+			allocateDailyTasks(plans, tt.total)
+
+			got := taskCounts(plans)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("task counts = %v, want %v", got, tt.want)
+			}
+			sum := 0
+			for _, c := range got {
+				sum += c
+			}
+			if len(plans) > 0 && sum != tt.total {
+				t.Errorf("allocated %d tasks, want all %d", sum, tt.total)
+			}
+		})
+	}
+}
+
+func TestBuildGoalPlans(t *testing.T) {
+	dbErr := errors.New("koneksi terputus")
+	goals := []repository.Goal{{ID: "g0", Priority: 3}, {ID: "g1", Priority: 8}, {ID: "g2", Priority: 1}}
+
+	tests := []struct {
+		name      string
+		steps     map[string]error // nil berarti goal masih punya langkah tersisa
+		total     int
+		wantGoals []string
+		wantCount []int
+		wantErr   error
+	}{
+		{
+			name:      "goal with no pending steps gets no tasks",
+			steps:     map[string]error{"g1": pgx.ErrNoRows},
+			total:     4,
+			wantGoals: []string{"g0", "g2"},
+			wantCount: []int{3, 1},
+		},
+		{
+			name:      "all goals still have steps",
+			steps:     map[string]error{},
+			total:     3,
+			wantGoals: []string{"g0", "g1", "g2"},
+			wantCount: []int{1, 1, 1},
+		},
+		{
+			name:  "every roadmap finished",
+			steps: map[string]error{"g0": pgx.ErrNoRows, "g1": pgx.ErrNoRows, "g2": pgx.ErrNoRows},
+			total: 5,
+		},
+		{
+			name:    "repository error is returned",
+			steps:   map[string]error{"g2": dbErr},
+			total:   3,
+			wantErr: dbErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextStep := func(_ context.Context, goalID string) (*repository.RoadmapStep, error) {
+				if err := tt.steps[goalID]; err != nil {
+					return nil, err
+				}
+				return &repository.RoadmapStep{ID: "step-" + goalID, GoalID: goalID}, nil
+			}
+
+			plans, err := buildGoalPlans(context.Background(), goals, nextStep)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || plans != nil {
+					t.Fatalf("got plans %v err %v, want err %v", plans, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			allocateDailyTasks(plans, tt.total)
+			var gotGoals []string
+			for _, p := range plans {
+				gotGoals = append(gotGoals, p.Goal.ID)
+				if p.Step == nil || p.Step.GoalID != p.Goal.ID {
+					t.Errorf("plan for %s has step %+v", p.Goal.ID, p.Step)
+				}
+			}
+			if fmt.Sprint(gotGoals) != fmt.Sprint(tt.wantGoals) || fmt.Sprint(taskCounts(plans)) != fmt.Sprint(tt.wantCount) {
+				t.Errorf("got goals %v with counts %v, want %v with %v", gotGoals, taskCounts(plans), tt.wantGoals, tt.wantCount)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package service
 import (
 	"context"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/ItsKevinRafaell/go-momentum-api/internal/repository"
@@ -18,9 +19,16 @@ type TaskService struct {
 	roadmapRepo *repository.RoadmapRepository
 	aiService   *AIService
 	reviewRepo  *repository.ReviewRepository
+	quota       *QuotaService
+	streaks     *StreakService
+	goals       *GoalService // Dipakai untuk menyelesaikan langkah (dan goal) secara otomatis
+	contextDays int          // Berapa hari riwayat tugas yang dikirim ke AI
+
+	dailyTaskCount int // Total tugas AI per hari, dibagi ke semua goal aktif
+	stepRule       stepCompletionRule
 }
 
-func NewTaskService(db *pgxpool.Pool, taskRepo *repository.TaskRepository, goalRepo *repository.GoalRepository, roadmapRepo *repository.RoadmapRepository, aiService *AIService, reviewRepo *repository.ReviewRepository) *TaskService {
+func NewTaskService(db *pgxpool.Pool, taskRepo *repository.TaskRepository, goalRepo *repository.GoalRepository, roadmapRepo *repository.RoadmapRepository, aiService *AIService, reviewRepo *repository.ReviewRepository, quota *QuotaService, streaks *StreakService, goals *GoalService) *TaskService {
 	return &TaskService{
 		db:          db,
 		taskRepo:    taskRepo,
@@ -28,6 +36,13 @@ func NewTaskService(db *pgxpool.Pool, taskRepo *repository.TaskRepository, goalR
 		roadmapRepo: roadmapRepo,
 		aiService:   aiService,
 		reviewRepo:  reviewRepo,
+		quota:       quota,
+		streaks:     streaks,
+		goals:       goals,
+		contextDays: intFromConfig("AI_CONTEXT_DAYS", 3),
+
+		dailyTaskCount: intFromConfig("DAILY_TASK_COUNT", 4),
+		stepRule:       stepCompletionRuleFromConfig(),
 	}
 }
 
@@ -87,60 +102,96 @@ func (s *TaskService) GetOrCreateTodaySchedule(ctx context.Context, userID strin
 
     log.Println("[DEBUG] Memulai pembuatan jadwal baru...")
 
-    // Cek Goal Aktif
-    activeGoal, err := s.goalRepo.GetActiveGoalByUserID(ctx, userID)
+    // Kumpulkan goal aktif yang masih punya langkah roadmap tersisa
+    activeGoals, err := s.goalRepo.GetActiveGoalsByUserID(ctx, userID)
     if err != nil {
         log.Printf("[DEBUG] Error saat mencari goal aktif: %v", err)
-        return []repository.Task{}, nil
+        return nil, err
     }
-    if activeGoal == nil {
-        log.Println("[DEBUG] Kondisi Gagal: Tidak ada goal aktif ditemukan.")
+    plans, err := buildGoalPlans(ctx, activeGoals, s.roadmapRepo.GetNextPendingStep)
+    if err != nil { return nil, err }
+    if len(plans) == 0 {
+        log.Println("[DEBUG] Kondisi Gagal: Tidak ada goal aktif dengan langkah roadmap tersisa.")
         return []repository.Task{}, nil
     }
-    log.Printf("[DEBUG] Ditemukan Goal Aktif: %s", activeGoal.Description)
 
-    // Cek Langkah Roadmap Berikutnya
-    currentStep, err := s.roadmapRepo.GetNextPendingStep(ctx, activeGoal.ID)
-    if err != nil {
-        if err == pgx.ErrNoRows {
-            log.Println("[DEBUG] Kondisi Gagal: Semua langkah roadmap sudah selesai.")
-            return []repository.Task{}, nil
+    // Bagi jumlah tugas hari ini ke setiap goal sesuai bobotnya
+    allocateDailyTasks(plans, s.dailyTaskCount)
+
+    // Pembuatan jadwal harian memakai satu kuota generasi AI, berapa pun jumlah goal-nya,
+    // dan hanya jika provider AI benar-benar dipanggil
+    aiCtx := s.quota.WithGenerationCharge(ctx, userID)
+
+    // AI dipanggil untuk semua goal lebih dulu, di luar transaksi agar koneksi database tidak
+    // tertahan selama menunggu AI
+    var newTasks []repository.Task
+    var startedSteps []string
+    for _, plan := range plans {
+        if plan.TaskCount == 0 {
+            continue
         }
-        log.Printf("[DEBUG] Error saat mencari langkah roadmap: %v", err)
-        return nil, err
-    }
-    log.Printf("[DEBUG] Ditemukan Langkah Roadmap Aktif: %s", currentStep.Title)
+        taskCount := min(plan.TaskCount, dailyTaskSchema.MaxItems)
+        log.Printf("[DEBUG] Goal %q, langkah %q: %d tugas.", plan.Goal.Description, plan.Step.Title, taskCount)
 
-    // Dapatkan tugas kemarin (tidak berubah)
-    yesterday := targetDate.AddDate(0, 0, -1)
-    yesterdayTasks, err := s.taskRepo.GetTasksByDate(ctx, userID, yesterday)
-    if err != nil { return nil, err }
+        // Rangkum riwayat beberapa hari terakhir sebagai konteks untuk AI
+        dailyCtx, err := s.buildDailyTaskContext(ctx, userID, plan.Step.ID, targetDate, s.contextDays)
+        if err != nil { return nil, err }
 
-    // Panggil AI
-    log.Println("[DEBUG] Memanggil AI Gemini untuk tugas harian...")
-    newTasksFromAI, err := s.aiService.GenerateDailyTasksWithAI(ctx, activeGoal.Description, currentStep.Title, yesterdayTasks)
-    if err != nil {
-        log.Printf("[DEBUG] Error dari panggilan AI: %v", err)
-        return nil, err
-    }
-    log.Printf("[DEBUG] AI berhasil merespons dengan %d tugas.", len(newTasksFromAI))
+        // Panggil AI (dengan template cadangan jika AI gagal)
+        // Sub-langkah diberi konteks judul langkah utamanya
+        stepTitle := plan.Step.Title
+        var parent *repository.RoadmapStep
+        if plan.Step.ParentID != nil {
+            parent, err = s.roadmapRepo.GetStepByID(ctx, *plan.Step.ParentID)
+            if err != nil { return nil, err }
+            stepTitle = parent.Title + ": " + plan.Step.Title
+        }
 
-    if len(newTasksFromAI) == 0 {
-        log.Println("[DEBUG] Kondisi Gagal: AI tidak menghasilkan tugas apapun.")
-        return []repository.Task{}, nil
+        newTasksFromAI, source, err := s.aiService.DailyTasksWithFallback(withAIScope(aiCtx, userID, plan.Goal.ID), plan.Goal.Description, stepTitle, taskCount, dailyCtx)
+        if err != nil {
+            log.Printf("[DEBUG] Error dari panggilan AI: %v", err)
+            return nil, err
+        }
+        log.Printf("[DEBUG] Berhasil mendapatkan %d tugas (sumber: %s).", len(newTasksFromAI), source)
+
+        for _, taskToCreate := range newTasksFromAI {
+            taskToCreate.UserID = userID
+            taskToCreate.Status = "pending"
+            taskToCreate.ScheduledDate = targetDate
+            taskToCreate.RoadmapStepID = &plan.Step.ID
+            taskToCreate.Source = source
+            newTasks = append(newTasks, taskToCreate)
+        }
+
+        // Langkah mulai dikerjakan begitu tugas pertamanya dijadwalkan
+        if len(newTasksFromAI) > 0 && plan.Step.Status == "pending" {
+            startedSteps = append(startedSteps, plan.Step.ID)
+        }
+        if len(newTasksFromAI) > 0 && parent != nil && parent.Status == "pending" {
+            startedSteps = append(startedSteps, parent.ID)
+        }
     }
 
-    // Simpan tugas ke DB (tidak berubah)
+    // Semua tugas dan status langkah disimpan dalam satu transaksi, agar kegagalan di tengah
+    // tidak meninggalkan jadwal yang hanya berisi sebagian goal
     var createdTasks []repository.Task
-    for _, taskToCreate := range newTasksFromAI {
-        taskToCreate.UserID = userID
-        taskToCreate.Status = "pending"
-        taskToCreate.ScheduledDate = targetDate
-        taskToCreate.RoadmapStepID = &currentStep.ID
+    err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+        tasks, roadmap := s.taskRepo.WithTx(tx), s.roadmapRepo.WithTx(tx)
+        for i := range newTasks {
+            createdTask, err := tasks.CreateTask(ctx, &newTasks[i])
+            if err != nil { return err }
+            createdTasks = append(createdTasks, *createdTask)
+        }
+        for _, stepID := range startedSteps {
+            if err := roadmap.MarkStepInProgress(ctx, stepID); err != nil { return err }
+        }
+        return nil
+    })
+    if err != nil { return nil, err }
 
-        createdTask, err := s.taskRepo.CreateTask(ctx, &taskToCreate)
-        if err != nil { return nil, err }
-        createdTasks = append(createdTasks, *createdTask)
+    if len(createdTasks) == 0 {
+        log.Println("[DEBUG] Kondisi Gagal: AI tidak menghasilkan tugas apapun.")
+        return []repository.Task{}, nil
     }
 
     log.Printf("[DEBUG] Berhasil menyimpan %d tugas baru ke DB.", len(createdTasks))
@@ -150,17 +201,71 @@ func (s *TaskService) GetOrCreateTodaySchedule(ctx context.Context, userID strin
 
 // FinalizeDayReview sekarang menerima targetDate.
 func (s *TaskService) FinalizeDayReview(ctx context.Context, userID string, targetDate time.Time) ([]repository.TaskSummary, string, error) {
+	return s.finalizeDayReview(ctx, userID, targetDate, nil)
+}
+
+// ReviewStream menerima hasil review secara bertahap: ringkasan lebih dulu,
+// lalu potongan feedback AI selagi dihasilkan.
+type ReviewStream struct {
+	OnSummary func(summary []repository.TaskSummary) error
+	OnChunk   func(chunk string) error
+	// OnReset dipanggil jika AI gagal setelah sebagian feedback terkirim. Potongan yang sudah
+	// diterima harus dibuang; feedback pengganti dikirim bersama hasil akhir.
+	OnReset func() error
+}
+
+// StreamDayReview memfinalisasi hari seperti FinalizeDayReview, tetapi mengalirkan
+// feedback AI ke stream. Feedback lengkap disimpan setelah streaming selesai.
+func (s *TaskService) StreamDayReview(ctx context.Context, userID string, targetDate time.Time, stream *ReviewStream) ([]repository.TaskSummary, string, error) {
+	return s.finalizeDayReview(ctx, userID, targetDate, stream)
+}
+
+func (s *TaskService) finalizeDayReview(ctx context.Context, userID string, targetDate time.Time, stream *ReviewStream) ([]repository.TaskSummary, string, error) {
     err := s.taskRepo.FinalizeMissedTasks(ctx, userID, targetDate)
 	if err != nil { return nil, "", err }
 
 	summary, err := s.taskRepo.GetTaskSummaryByDate(ctx, userID, targetDate)
 	if err != nil { return nil, "", err }
-	
-	activeGoal, _ := s.goalRepo.GetActiveGoalByUserID(ctx, userID)
-	if activeGoal == nil { activeGoal = &repository.Goal{ Description: "mencapai tujuan mereka" } }
 
-	feedback, err := s.aiService.GenerateReviewFeedback(ctx, activeGoal.Description, summary)
-	if err != nil { feedback = "Tetap semangat untuk esok hari!" }
+	// Streak dihitung dari ringkasan final; kegagalan di sini tidak membatalkan review
+	if _, err := s.streaks.RecordDay(ctx, userID, targetDate, summary); err != nil {
+		log.Printf("ERROR updating streak for user %s: %v", userID, err)
+	}
+
+	if stream != nil {
+		if err := stream.OnSummary(summary); err != nil { return nil, "", err }
+	}
+	
+	// Feedback mencakup semua goal aktif; log AI diatribusikan ke goal utama
+	activeGoals, _ := s.goalRepo.GetActiveGoalsByUserID(ctx, userID)
+	var primaryGoalID string
+	var descriptions []string
+	for i, goal := range activeGoals {
+		if i == 0 { primaryGoalID = goal.ID }
+		descriptions = append(descriptions, goal.Description)
+	}
+	goalDesc := strings.Join(descriptions, "; ") // Template prompt punya kalimat default jika goal kosong
+
+	aiCtx := withAIScope(ctx, userID, primaryGoalID)
+	var feedback string
+	if stream != nil {
+		streamed := false
+		feedback, err = s.aiService.StreamReviewFeedback(aiCtx, goalDesc, summary, func(chunk string) error {
+			streamed = true
+			return stream.OnChunk(chunk)
+		})
+		if err != nil && streamed && stream.OnReset != nil {
+			if resetErr := stream.OnReset(); resetErr != nil {
+				log.Printf("Gagal mengirim reset feedback review: %v", resetErr)
+			}
+		}
+	} else {
+		feedback, err = s.aiService.GenerateReviewFeedback(aiCtx, goalDesc, summary)
+	}
+	if err != nil {
+		log.Printf("Gagal membuat feedback review, memakai feedback default: %v", err)
+		feedback = s.aiService.DefaultReviewFeedback(aiCtx)
+	}
 
 	review := &repository.DailyReview{
 		UserID:      userID,
@@ -168,7 +273,8 @@ func (s *TaskService) FinalizeDayReview(ctx context.Context, userID string, targ
 		Summary:     summary,
 		AIFeedback:  feedback,
 	}
-	if err := s.reviewRepo.CreateOrUpdateReview(ctx, review); err != nil {
+	// Tetap simpan review walaupun klien streaming sudah memutus koneksi
+	if err := s.reviewRepo.CreateOrUpdateReview(context.WithoutCancel(ctx), review); err != nil {
 		log.Printf("ERROR saving daily review for date %v: %v", targetDate, err)
 	}
 
@@ -190,12 +296,48 @@ func (s *TaskService) CreateManualTask(ctx context.Context, userID, title string
 		Status:        "pending",
 		ScheduledDate: today,
 		Deadline:      deadline,
+		Source:        repository.SourceManual,
 	}
 	return s.taskRepo.CreateTask(ctx, newTask)
 }
 
-func (s *TaskService) UpdateTaskStatus(ctx context.Context, userID string, taskID string, status string) error {
-	return s.taskRepo.UpdateTaskStatus(ctx, userID, taskID, status)
+// UpdateTaskStatus mengubah status tugas. Jika tugas terhubung ke langkah roadmap, progres
+// langkah itu dihitung ulang dan dikembalikan (nil untuk tugas manual).
+func (s *TaskService) UpdateTaskStatus(ctx context.Context, userID string, taskID string, status string) (*StepProgress, error) {
+	if _, ok := taskTransitions[status]; !ok {
+		return nil, ErrInvalidTaskStatus
+	}
+	task, err := s.taskRepo.GetTaskByID(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != status {
+		if !canTransition(taskTransitions, task.Status, status) {
+			return nil, &StatusTransitionError{Entity: "task", From: task.Status, To: status}
+		}
+		if err := s.taskRepo.UpdateTaskStatus(ctx, userID, taskID, task.Status, status); err != nil {
+			return nil, err
+		}
+	}
+	if task.RoadmapStepID == nil {
+		return nil, nil
+	}
+
+	// Status tugas sudah tersimpan; kegagalan menghitung progres langkah tidak membatalkannya
+	progress, err := s.advanceStep(ctx, userID, *task.RoadmapStepID)
+	if err != nil {
+		log.Printf("ERROR advancing roadmap step %s: %v", *task.RoadmapStepID, err)
+		return nil, nil
+	}
+	return progress, nil
+}
+
+// GetTaskHistory mengembalikan riwayat status tugas milik user.
+func (s *TaskService) GetTaskHistory(ctx context.Context, userID, taskID string) ([]repository.StatusChange, error) {
+	if _, err := s.taskRepo.GetTaskByID(ctx, userID, taskID); err != nil {
+		return nil, err
+	}
+	return s.taskRepo.GetTaskStatusHistory(ctx, userID, taskID)
 }
 
 func (s *TaskService) UpdateTaskDeadline(ctx context.Context, userID, taskID string, deadline time.Time) error {
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenState adalah status token user yang di-cache untuk JwtMiddleware.
+type tokenState struct {
+	version int
+	locked  bool
+	missing bool // User sudah dihapus
+	expires time.Time
+}
+
+// tokenStateCache menyimpan token_version per user selama ttl agar middleware tidak perlu
+// query ke database di setiap request. Perubahan dari instance lain baru terlihat setelah
+// entri kedaluwarsa; perubahan di instance ini langsung dihapus lewat invalidate.
+//
+// generation naik setiap invalidate. Pembaca mengambil generation sebelum query ke database
+// dan put diabaikan jika sudah ada invalidate sejak itu, agar hasil query yang dibaca sebelum
+// perubahan tidak masuk lagi ke cache setelah entrinya dihapus.
+type tokenStateCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]tokenState
+	generation uint64
+}
+
+func newTokenStateCache(ttl time.Duration) *tokenStateCache {
+	return &tokenStateCache{ttl: ttl, maxEntries: 10000, entries: map[string]tokenState{}}
+}
+
+func (c *tokenStateCache) get(userID string, now time.Time) (tokenState, bool) {
+	if c.ttl <= 0 {
+		return tokenState{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.entries[userID]
+	if !ok || now.After(state.expires) {
+		return tokenState{}, false
+	}
+	return state, true
+}
+
+// currentGeneration dipanggil sebelum membaca status token dari database; hasilnya diteruskan ke put.
+func (c *tokenStateCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+func (c *tokenStateCache) put(userID string, state tokenState, generation uint64, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if len(c.entries) >= c.maxEntries {
+		for id, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			c.entries = map[string]tokenState{}
+		}
+	}
+	state.expires = now.Add(c.ttl)
+	c.entries[userID] = state
+}
+
+func (c *tokenStateCache) invalidate(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	delete(c.entries, userID)
+}